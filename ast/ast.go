@@ -0,0 +1,115 @@
+// Package ast declares types of expression syntax tree nodes.
+package ast
+
+// Node is node of syntax tree. Positions are byte offsets at expression,
+// End is position after last char of node.
+type Node interface {
+	Pos() int
+	End() int
+}
+
+// Literal is number, string or bool literal. Value is int, float64, string or bool.
+type Literal struct {
+	ValuePos int
+	ValueEnd int
+	Value    any
+}
+
+// Ident is name of variable or bareword key, or name of called function or method.
+type Ident struct {
+	NamePos int
+	Name    string
+}
+
+// Binary is binary operator expression `X Op Y`.
+type Binary struct {
+	X     Node
+	OpPos int
+	Op    string
+	Y     Node
+}
+
+// Unary is prefix operator expression `Op X`.
+type Unary struct {
+	OpPos int
+	Op    string
+	X     Node
+}
+
+// Call is function call `Fun(Args)`. Fun is Ident for function and Member for method call.
+type Call struct {
+	Fun    Node
+	Args   []Node
+	Rparen int // Position of closing parenthesis.
+}
+
+// Paren is parenthesized expression `(X)`.
+type Paren struct {
+	Lparen int // Position of opening parenthesis.
+	X      Node
+	Rparen int // Position of closing parenthesis.
+}
+
+// Member is member access `X.Sel`. Sel is Ident for bareword key or any other expression.
+type Member struct {
+	X   Node
+	Sel Node
+}
+
+// Index is index expression `X[Index]`.
+type Index struct {
+	X      Node
+	Index  Node
+	Rbrack int // Position of closing square bracket.
+}
+
+// Conditional is conditional expression `Cond ? Then : Else`.
+type Conditional struct {
+	Cond Node
+	Then Node
+	Else Node
+}
+
+// Array is array literal `[Items]`.
+type Array struct {
+	Lbrack int // Position of opening square bracket.
+	Items  []Node
+	Rbrack int // Position of closing square bracket.
+}
+
+// MapEntry is key and value of map literal.
+type MapEntry struct {
+	Key   Node
+	Value Node
+}
+
+// Map is map literal `{Key: Value, ...}`.
+type Map struct {
+	Lbrace  int // Position of opening curly bracket.
+	Entries []MapEntry
+	Rbrace  int // Position of closing curly bracket.
+}
+
+func (n *Literal) Pos() int     { return n.ValuePos }
+func (n *Ident) Pos() int       { return n.NamePos }
+func (n *Binary) Pos() int      { return n.X.Pos() }
+func (n *Unary) Pos() int       { return n.OpPos }
+func (n *Call) Pos() int        { return n.Fun.Pos() }
+func (n *Paren) Pos() int       { return n.Lparen }
+func (n *Member) Pos() int      { return n.X.Pos() }
+func (n *Index) Pos() int       { return n.X.Pos() }
+func (n *Conditional) Pos() int { return n.Cond.Pos() }
+func (n *Array) Pos() int       { return n.Lbrack }
+func (n *Map) Pos() int         { return n.Lbrace }
+
+func (n *Literal) End() int     { return n.ValueEnd }
+func (n *Ident) End() int       { return n.NamePos + len(n.Name) }
+func (n *Binary) End() int      { return n.Y.End() }
+func (n *Unary) End() int       { return n.X.End() }
+func (n *Call) End() int        { return n.Rparen + 1 }
+func (n *Paren) End() int       { return n.Rparen + 1 }
+func (n *Member) End() int      { return n.Sel.End() }
+func (n *Index) End() int       { return n.Rbrack + 1 }
+func (n *Conditional) End() int { return n.Else.End() }
+func (n *Array) End() int       { return n.Rbrack + 1 }
+func (n *Map) End() int         { return n.Rbrace + 1 }
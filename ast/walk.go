@@ -0,0 +1,66 @@
+package ast
+
+// Visitor is called by Walk for each node. If returned visitor w is not nil, Walk
+// visits children of node with w and then calls w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses syntax tree in depth first order. It starts with v.Visit(node).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	switch n := node.(type) {
+	case *Literal, *Ident:
+		// No children.
+	case *Binary:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+	case *Unary:
+		Walk(v, n.X)
+	case *Paren:
+		Walk(v, n.X)
+	case *Call:
+		Walk(v, n.Fun)
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+	case *Member:
+		Walk(v, n.X)
+		Walk(v, n.Sel)
+	case *Index:
+		Walk(v, n.X)
+		Walk(v, n.Index)
+	case *Conditional:
+		Walk(v, n.Cond)
+		Walk(v, n.Then)
+		Walk(v, n.Else)
+	case *Array:
+		for _, item := range n.Items {
+			Walk(v, item)
+		}
+	case *Map:
+		for _, e := range n.Entries {
+			Walk(v, e.Key)
+			Walk(v, e.Value)
+		}
+	}
+	v.Visit(nil)
+}
+
+// inspector is Visitor of Inspect.
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses syntax tree in depth first order. It calls f(node) for each node and
+// visits children of node if f returns true. After children f(nil) is called.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	// a.b(1) ? [x] : {k: -y}
+	tree := &Conditional{
+		Cond: &Call{
+			Fun:  &Member{X: &Ident{Name: "a"}, Sel: &Ident{Name: "b"}},
+			Args: []Node{&Literal{Value: 1}},
+		},
+		Then: &Array{Items: []Node{&Ident{Name: "x"}}},
+		Else: &Map{Entries: []MapEntry{
+			{Key: &Ident{Name: "k"}, Value: &Unary{Op: "-", X: &Ident{Name: "y"}}},
+		}},
+	}
+	tests := []struct {
+		name string
+		skip string // Type of node which children are skipped.
+		want []string
+	}{
+		{
+			name: "all nodes",
+			want: []string{
+				"*ast.Conditional", "*ast.Call", "*ast.Member", "a", "b", "1",
+				"*ast.Array", "x", "*ast.Map", "k", "*ast.Unary", "y",
+			},
+		},
+		{
+			name: "skip call",
+			skip: "*ast.Call",
+			want: []string{
+				"*ast.Conditional", "*ast.Call", "*ast.Array", "x", "*ast.Map", "k", "*ast.Unary", "y",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			Inspect(tree, func(n Node) bool {
+				switch n := n.(type) {
+				case nil:
+					return false
+				case *Ident:
+					got = append(got, n.Name)
+				case *Literal:
+					got = append(got, fmt.Sprint(n.Value))
+				default:
+					got = append(got, fmt.Sprintf("%T", n))
+				}
+				return fmt.Sprintf("%T", n) != tt.skip
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Inspect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
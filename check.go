@@ -0,0 +1,412 @@
+package lexpr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Type is static type of expression value.
+type Type int
+
+const (
+	TypeAny    Type = iota // Type is unknown until evaluation.
+	TypeNumber             // Int or float.
+	TypeInt
+	TypeFloat
+	TypeString
+	TypeBool
+	TypeArray
+	TypeMap
+	TypeObject
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeNumber:
+		return "number"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeArray:
+		return "array"
+	case TypeMap:
+		return "map"
+	case TypeObject:
+		return "object"
+	}
+	return "any"
+}
+
+// isNumeric returns true if value of type can be number.
+func (t Type) isNumeric() bool {
+	return t == TypeAny || t == TypeNumber || t == TypeInt || t == TypeFloat
+}
+
+// accepts returns true if value of type v can be passed where type t is expected.
+func (t Type) accepts(v Type) bool {
+	switch {
+	case t == TypeAny || v == TypeAny || t == v:
+		return true
+	case t == TypeNumber || t == TypeFloat:
+		return v.isNumeric()
+	case t == TypeInt:
+		return v == TypeNumber
+	}
+	return false
+}
+
+// Signature declares types of function parameters and result. If Variadic is true,
+// last parameter type is type of all rest arguments.
+type Signature struct {
+	Params   []Type
+	Variadic bool
+	Result   Type
+}
+
+// TypeEnv declares types of variables and signatures of functions for Check. Types of
+// not declared variables are taken from Lexpr variables. Signatures of functions set by
+// RegisterFunc and default functions are known without declaration.
+type TypeEnv struct {
+	Variables map[string]Type
+	Functions map[string]Signature
+}
+
+// Check compiles expression and checks types of its operands and arguments without
+// evaluation. Returns type of expression result.
+func (l *Lexpr) Check(expression string, env TypeEnv) (Type, error) {
+	p, err := l.Compile(expression)
+	if err != nil {
+		return TypeAny, err
+	}
+	return p.Check(env)
+}
+
+// Check checks types of program operands and arguments without evaluation. Returns type
+// of program result. Returned error has position of erroneous token at expression.
+func (p *Program) Check(env TypeEnv) (Type, error) {
+	c := &checker{
+		l:        p.l.snapshot(),
+		env:      env,
+		branches: map[int64]Type{},
+	}
+	for _, tkn := range p.tokens {
+		if err := c.step(tkn); err != nil {
+			return TypeAny, newError(p.expr, tkn, err)
+		}
+	}
+	if len(c.stack) == 0 {
+		return TypeAny, nil
+	}
+	return c.stack[len(c.stack)-1], nil
+}
+
+// checker holds state of types checking of rpn tokens.
+type checker struct {
+	l        *Lexpr
+	env      TypeEnv
+	stack    []Type         // Types of values at evaluation stack.
+	branches map[int64]Type // Types of then branches of conditional expressions by end label.
+}
+
+// step checks one rpn token. Branches of conditional expression are checked both.
+func (c *checker) step(tkn Token) error {
+	switch tkn.typ {
+	case number:
+		c.push(TypeInt)
+	case float:
+		c.push(TypeFloat)
+	case str:
+		c.push(TypeString)
+	case boolean:
+		c.push(TypeBool)
+	case word:
+		c.push(c.variableType(tkn.value))
+	case cjmp:
+		if t := c.pop(); !TypeBool.accepts(t) {
+			return fmt.Errorf("%w: condition must be bool, got %s", ErrTypeMismatch, t)
+		}
+	case jmp:
+		// End of then branch, else branch starts from same stack.
+		c.branches[tkn.ivalue] = c.pop()
+	case label:
+		if then, ok := c.branches[tkn.ivalue]; ok {
+			c.push(unifyTypes(then, c.pop()))
+		}
+	case op:
+		return c.operator(c.l.operators[tkn.value], 2)
+	case prefix:
+		return c.operator(c.l.prefixOperators[tkn.value], 1)
+	case funct:
+		args := c.popN(int(tkn.ivalue))
+		sig, ok := c.env.Functions[tkn.value]
+		if !ok {
+			fn := c.l.functions[tkn.value]
+			if tkn.call.fn != nil {
+				fn = *tkn.call.fn
+			}
+			if fn.signature != nil {
+				sig, ok = *fn.signature, true
+			}
+		}
+		if !ok {
+			c.push(TypeAny)
+			return nil
+		}
+		for i, arg := range args {
+			param := TypeAny
+			switch {
+			case i < len(sig.Params)-1 || i < len(sig.Params) && !sig.Variadic:
+				param = sig.Params[i]
+			case sig.Variadic && len(sig.Params) > 0:
+				param = sig.Params[len(sig.Params)-1]
+			}
+			if !param.accepts(arg) {
+				return fmt.Errorf("%w: argument %d of %s: want %s, got %s", ErrTypeMismatch, i+1, tkn.value, param, arg)
+			}
+		}
+		c.push(sig.Result)
+	case method:
+		c.popN(int(tkn.ivalue))
+		if t := c.pop(); !TypeObject.accepts(t) {
+			return fmt.Errorf("%w: method %s of %s", ErrTypeMismatch, tkn.value, t)
+		}
+		c.push(TypeAny)
+	case lb:
+		c.popN(int(tkn.ivalue))
+		c.push(TypeArray)
+	case lc:
+		items := c.popN(2 * int(tkn.ivalue))
+		for i := 0; i < len(items); i += 2 {
+			if !TypeString.accepts(items[i]) {
+				return fmt.Errorf("%w: map key must be string, got %s", ErrTypeMismatch, items[i])
+			}
+		}
+		c.push(TypeMap)
+	case index:
+		key := c.pop()
+		t, err := memberType(c.pop(), key)
+		if err != nil {
+			return err
+		}
+		c.push(t)
+	case tokError:
+		return tkn.err
+	}
+	return nil
+}
+
+// operator checks operands of operator and pushes type of result. Operators without
+// types rule returns any type.
+func (c *checker) operator(o Operator, arity int) error {
+	args := c.popN(arity)
+	if o.check == nil {
+		c.push(TypeAny)
+		return nil
+	}
+	t, err := o.check(args...)
+	if err != nil {
+		return err
+	}
+	c.push(t)
+	return nil
+}
+
+// variableType returns declared type of variable or type of Lexpr variable value.
+// Unknown identifiers has any type, as they can be used as keys.
+func (c *checker) variableType(name string) Type {
+	if t, ok := c.env.Variables[name]; ok {
+		return t
+	}
+	lower := strings.ToLower(name)
+	if t, ok := c.env.Variables[lower]; ok {
+		return t
+	}
+	if v, ok := c.l.variables[lower]; ok {
+		if t, ok := TokenFromAny(v); ok {
+			return tokenType(t)
+		}
+	}
+	return TypeAny
+}
+
+func (c *checker) push(t Type) {
+	c.stack = append(c.stack, t)
+}
+
+// pop returns type from top of stack. Missing operands has any type, count of operands
+// is checked at evaluation.
+func (c *checker) pop() Type {
+	if len(c.stack) == 0 {
+		return TypeAny
+	}
+	t := c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+	return t
+}
+
+// popN returns n types from top of stack in order of pushing.
+func (c *checker) popN(n int) []Type {
+	types := make([]Type, n)
+	for i := n - 1; i >= 0; i-- {
+		types[i] = c.pop()
+	}
+	return types
+}
+
+// unifyTypes returns type that includes both types.
+func unifyTypes(t1, t2 Type) Type {
+	switch {
+	case t1 == t2:
+		return t1
+	case t1 != TypeAny && t2 != TypeAny && t1.isNumeric() && t2.isNumeric():
+		return TypeNumber
+	}
+	return TypeAny
+}
+
+// tokenType returns static type of token value.
+func tokenType(t Token) Type {
+	switch t.typ {
+	case number:
+		return TypeInt
+	case float:
+		return TypeFloat
+	case str:
+		return TypeString
+	case boolean:
+		return TypeBool
+	case array:
+		return TypeArray
+	case dict:
+		return TypeMap
+	case object:
+		return TypeObject
+	}
+	return TypeAny
+}
+
+// reflectType returns static type of Go values of type rt.
+func reflectType(rt reflect.Type) Type {
+	switch rt.Kind() {
+	case reflect.String:
+		return TypeString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return TypeInt
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat
+	case reflect.Bool:
+		return TypeBool
+	case reflect.Slice, reflect.Array:
+		return TypeArray
+	case reflect.Map:
+		return TypeMap
+	case reflect.Struct:
+		return TypeObject
+	case reflect.Pointer:
+		if rt.Elem().Kind() == reflect.Struct {
+			return TypeObject
+		}
+		return reflectType(rt.Elem())
+	}
+	return TypeAny
+}
+
+// funcSignature returns signature of Go function type. Trailing error result is skipped,
+// many results are returned as array.
+func funcSignature(ft reflect.Type) *Signature {
+	sig := &Signature{
+		Params:   make([]Type, ft.NumIn()),
+		Variadic: ft.IsVariadic(),
+	}
+	for i := range sig.Params {
+		in := ft.In(i)
+		if sig.Variadic && i == ft.NumIn()-1 {
+			in = in.Elem()
+		}
+		sig.Params[i] = reflectType(in)
+	}
+	numOut := ft.NumOut()
+	if numOut > 0 && ft.Out(numOut-1) == errorType {
+		numOut--
+	}
+	switch numOut {
+	case 0:
+		sig.Result = TypeAny
+	case 1:
+		sig.Result = reflectType(ft.Out(0))
+	default:
+		sig.Result = TypeArray
+	}
+	return sig
+}
+
+// memberType checks type of member access operand. Members of strings are JSON values.
+func memberType(obj, key Type) (Type, error) {
+	switch obj {
+	case TypeAny, TypeString, TypeMap, TypeObject:
+		return TypeAny, nil
+	case TypeArray:
+		if !TypeInt.accepts(key) {
+			return TypeAny, fmt.Errorf("%w: array index must be int, got %s", ErrTypeMismatch, key)
+		}
+		return TypeAny, nil
+	}
+	return TypeAny, fmt.Errorf("%w: %s has no members", ErrTypeMismatch, obj)
+}
+
+// mathType is types rule of math operators.
+func mathType(args ...Type) (Type, error) {
+	t1, t2 := args[0], args[1]
+	switch {
+	case !t1.isNumeric() || !t2.isNumeric():
+		return TypeAny, fmt.Errorf("%w: want number, got %s and %s", ErrTypeMismatch, t1, t2)
+	case t1 == TypeInt && t2 == TypeInt:
+		return TypeInt, nil
+	case t1 == TypeFloat || t2 == TypeFloat:
+		return TypeFloat, nil
+	}
+	return TypeNumber, nil
+}
+
+// compareType is types rule of numbers comparison operators.
+func compareType(args ...Type) (Type, error) {
+	if !args[0].isNumeric() || !args[1].isNumeric() {
+		return TypeAny, fmt.Errorf("%w: cannot compare %s with %s", ErrTypeMismatch, args[0], args[1])
+	}
+	return TypeBool, nil
+}
+
+// equalType is types rule of equality operators. Values of any types can be compared.
+func equalType(args ...Type) (Type, error) {
+	return TypeBool, nil
+}
+
+// logicType is types rule of logic operators.
+func logicType(args ...Type) (Type, error) {
+	for _, t := range args {
+		if !TypeBool.accepts(t) {
+			return TypeAny, fmt.Errorf("%w: want bool, got %s", ErrTypeMismatch, t)
+		}
+	}
+	return TypeBool, nil
+}
+
+// numberType is types rule of numeric prefix operators.
+func numberType(args ...Type) (Type, error) {
+	if !args[0].isNumeric() {
+		return TypeAny, fmt.Errorf("%w: want number, got %s", ErrTypeMismatch, args[0])
+	}
+	return args[0], nil
+}
+
+// memberOperatorType is types rule of member access operator.
+func memberOperatorType(args ...Type) (Type, error) {
+	return memberType(args[0], args[1])
+}
@@ -0,0 +1,184 @@
+package lexpr
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLexpr_Check(t *testing.T) {
+	env := TypeEnv{
+		Variables: map[string]Type{
+			"price":  TypeFloat,
+			"count":  TypeInt,
+			"name":   TypeString,
+			"active": TypeBool,
+			"items":  TypeArray,
+		},
+		Functions: map[string]Signature{
+			"discount": {Params: []Type{TypeString}, Result: TypeFloat},
+			"sum":      {Params: []Type{TypeNumber}, Variadic: true, Result: TypeNumber},
+		},
+	}
+	tests := []struct {
+		name       string
+		expression string
+		want       Type
+		wantErr    string
+	}{
+		{
+			name:       "int math",
+			expression: "1 + 2 * count",
+			want:       TypeInt,
+		},
+		{
+			name:       "float math",
+			expression: "price * count",
+			want:       TypeFloat,
+		},
+		{
+			name:       "compare string with number",
+			expression: `count > 1 && name > 3`,
+			wantErr:    "type mismatch: cannot compare string with int at 18:19",
+		},
+		{
+			name:       "logic",
+			expression: "count > 18 && active || !active",
+			want:       TypeBool,
+		},
+		{
+			name:       "logic with number",
+			expression: "active && count",
+			wantErr:    "type mismatch: want bool, got int at 7:9",
+		},
+		{
+			name:       "math with string",
+			expression: `price - name`,
+			wantErr:    "type mismatch: want number, got float and string at 6:7",
+		},
+		{
+			name:       "prefix operator",
+			expression: `-name`,
+			wantErr:    "type mismatch: want number, got string at 0:1",
+		},
+		{
+			name:       "conditional numbers",
+			expression: "active ? price : count",
+			want:       TypeNumber,
+		},
+		{
+			name:       "conditional of different types",
+			expression: `active ? name : count`,
+			want:       TypeAny,
+		},
+		{
+			name:       "not bool condition",
+			expression: `count ? 1 : 2`,
+			wantErr:    "type mismatch: condition must be bool, got int at 6:7",
+		},
+		{
+			name:       "std function",
+			expression: `itoa(count) + "x"`,
+			wantErr:    "type mismatch: want number, got string and string at 12:13",
+		},
+		{
+			name:       "std function argument",
+			expression: `itoa(price)`,
+			wantErr:    "type mismatch: argument 1 of itoa: want int, got float at 0:11",
+		},
+		{
+			name:       "declared function",
+			expression: `discount(name) * sum(1, price, count)`,
+			want:       TypeFloat,
+		},
+		{
+			name:       "declared variadic function argument",
+			expression: `sum(1, 2, name)`,
+			wantErr:    "type mismatch: argument 3 of sum: want number, got string at 0:15",
+		},
+		{
+			name:       "registered function",
+			expression: `hypot(3, count) > 1`,
+			want:       TypeBool,
+		},
+		{
+			name:       "registered function argument",
+			expression: `hypot(3, "4")`,
+			wantErr:    "type mismatch: argument 2 of hypot: want float, got string at 0:13",
+		},
+		{
+			name:       "json member",
+			expression: `jsonData.key.0 == "x"`,
+			want:       TypeBool,
+		},
+		{
+			name:       "member of number",
+			expression: `count.key`,
+			wantErr:    "type mismatch: int has no members at 5:6",
+		},
+		{
+			name:       "array index",
+			expression: `items[count] + [1, 2][0]`,
+			want:       TypeNumber,
+		},
+		{
+			name:       "string array index",
+			expression: `items["0"]`,
+			wantErr:    "type mismatch: array index must be int, got string at 5:10",
+		},
+		{
+			name:       "map key",
+			expression: `{count: 1}`,
+			wantErr:    "type mismatch: map key must be string, got int at 0:10",
+		},
+		{
+			name:       "unknown identifiers",
+			expression: `a + b`,
+			want:       TypeNumber,
+		},
+		{
+			name:       "syntax error",
+			expression: `(1 + 2`,
+			wantErr:    "syntax error: invalid brakets at 0:1",
+		},
+	}
+	functions := map[string]Function{}
+	for name, fn := range Functions {
+		functions[name] = fn
+	}
+	l := New(
+		WithOperators(Operators),
+		WithPrefixOperators(PrefixOperators),
+		WithFunctions(functions),
+		WithValues(map[string]any{"jsondata": `{"key": ["x"]}`}),
+	)
+	l.SetFunc("discount", func(args []Token) (Token, error) {
+		return TokenFromFloat(0.1), nil
+	}, 1, 1)
+	l.SetFunc("sum", func(args []Token) (Token, error) {
+		return TokenFromInt(len(args)), nil
+	}, 0, -1)
+	if err := l.RegisterFunc("hypot", math.Hypot); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Check(tt.expression, env)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Lexpr.Check() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil && !errors.Is(err, ErrTypeMismatch) && !errors.Is(err, ErrSyntax) {
+					t.Errorf("Lexpr.Check() error = %v, want sentinel error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lexpr.Check() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Lexpr.Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package lexpr
+
+import (
+	"fmt"
+	"strings"
+
+	"go.neonxp.dev/lexpr/ast"
+)
+
+// Dependencies of expression, in order of first use.
+type Dependencies struct {
+	Identifiers []string // Identifiers used as values, roots of paths included.
+	Paths       []string // Static member paths from identifiers, like `jsonData.rootKey2.childKey2`.
+	Functions   []string // Called functions.
+	Unresolved  []string // Identifiers that are not Lexpr variables. They must be passed on evaluation.
+}
+
+// Dependencies returns identifiers, member paths and functions used by expression. Bareword
+// keys, like `rootKey2` at `jsonData.rootKey2`, are parts of paths, not identifiers, unless
+// there is Lexpr variable with same name.
+func (l *Lexpr) Dependencies(expression string) (Dependencies, error) {
+	node, err := l.Parse(expression)
+	if err != nil {
+		return Dependencies{}, err
+	}
+	c := &depsCollector{
+		l:    l.snapshot(),
+		seen: map[string]bool{},
+	}
+	if node != nil {
+		c.visit(node)
+	}
+	return c.deps, nil
+}
+
+// depsCollector collects dependencies from syntax tree.
+type depsCollector struct {
+	l    *Lexpr
+	deps Dependencies
+	seen map[string]bool // Added dependencies by kind and name.
+}
+
+func (c *depsCollector) visit(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.Ident:
+		c.addIdent(n.Name)
+	case *ast.Member, *ast.Index:
+		if path, ok := c.path(n); ok {
+			c.addPath(path)
+		}
+	case *ast.Call:
+		switch fn := n.Fun.(type) {
+		case *ast.Ident:
+			c.add(&c.deps.Functions, "func", fn.Name)
+		case *ast.Member:
+			// Method call, receiver is dependency.
+			c.visit(fn.X)
+		}
+		for _, arg := range n.Args {
+			c.visit(arg)
+		}
+	case *ast.Binary:
+		c.visit(n.X)
+		c.visit(n.Y)
+	case *ast.Unary:
+		c.visit(n.X)
+	case *ast.Paren:
+		c.visit(n.X)
+	case *ast.Conditional:
+		c.visit(n.Cond)
+		c.visit(n.Then)
+		c.visit(n.Else)
+	case *ast.Array:
+		for _, item := range n.Items {
+			c.visit(item)
+		}
+	case *ast.Map:
+		for _, e := range n.Entries {
+			if _, ok := c.key(e.Key); !ok {
+				c.visit(e.Key)
+			}
+			c.visit(e.Value)
+		}
+	}
+}
+
+// path returns static member path of node. If path has dynamic part, static prefix
+// of path is added and false is returned.
+func (c *depsCollector) path(n ast.Node) ([]string, bool) {
+	var x, sel ast.Node
+	switch n := n.(type) {
+	case *ast.Ident:
+		c.addIdent(n.Name)
+		return []string{n.Name}, true
+	case *ast.Paren:
+		return c.path(n.X)
+	case *ast.Member:
+		x, sel = n.X, n.Sel
+	case *ast.Index:
+		x, sel = n.X, n.Index
+	default:
+		c.visit(n)
+		return nil, false
+	}
+	path, ok := c.path(x)
+	key, isKey := c.key(sel)
+	if _, isIndex := n.(*ast.Index); isIndex {
+		// Identifier at brackets is variable, only literals are static keys.
+		_, isLiteral := sel.(*ast.Literal)
+		isKey = isKey && isLiteral
+	}
+	if !isKey {
+		c.visit(sel)
+	}
+	switch {
+	case ok && isKey:
+		return append(path, key), true
+	case ok:
+		c.addPath(path)
+	}
+	return nil, false
+}
+
+// key returns static member key of node: literal or bareword.
+func (c *depsCollector) key(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.Literal:
+		return fmt.Sprint(n.Value), true
+	case *ast.Ident:
+		_, isVariable := c.l.variables[strings.ToLower(n.Name)]
+		return n.Name, !isVariable
+	}
+	return "", false
+}
+
+func (c *depsCollector) addIdent(name string) {
+	c.add(&c.deps.Identifiers, "ident", name)
+	if _, ok := c.l.variables[strings.ToLower(name)]; !ok {
+		c.add(&c.deps.Unresolved, "unresolved", name)
+	}
+}
+
+// addPath adds paths with at least one key.
+func (c *depsCollector) addPath(path []string) {
+	if len(path) > 1 {
+		c.add(&c.deps.Paths, "path", strings.Join(path, "."))
+	}
+}
+
+// add adds name of given kind to list once.
+func (c *depsCollector) add(list *[]string, kind, name string) {
+	if c.seen[kind+":"+name] {
+		return
+	}
+	c.seen[kind+":"+name] = true
+	*list = append(*list, name)
+}
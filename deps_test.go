@@ -0,0 +1,84 @@
+package lexpr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexpr_Dependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       Dependencies
+		wantErr    bool
+	}{
+		{
+			name:       "json paths",
+			expression: `jsonData.rootKey2.childKey2 == "value3" || len(jsonData.arrayKey[3]) > limit`,
+			want: Dependencies{
+				Identifiers: []string{"jsonData", "limit"},
+				Paths:       []string{"jsonData.rootKey2.childKey2", "jsonData.arrayKey.3"},
+				Functions:   []string{"len"},
+				Unresolved:  []string{"limit"},
+			},
+		},
+		{
+			name:       "dynamic key",
+			expression: `jsonData.key1name + jsonData.items[i].price`,
+			want: Dependencies{
+				Identifiers: []string{"jsonData", "key1name", "i"},
+				Paths:       []string{"jsonData.items"},
+				Unresolved:  []string{"i"},
+			},
+		},
+		{
+			name:       "method call",
+			expression: `user.profile.HasRole(role) ? max(a, b) : {status: "ok"}`,
+			want: Dependencies{
+				Identifiers: []string{"user", "role", "a", "b"},
+				Paths:       []string{"user.profile"},
+				Functions:   []string{"max"},
+				Unresolved:  []string{"user", "role", "a", "b"},
+			},
+		},
+		{
+			name:       "parentheses",
+			expression: `(jsonData.user).name * (price - 1)`,
+			want: Dependencies{
+				Identifiers: []string{"jsonData", "price"},
+				Paths:       []string{"jsonData.user.name"},
+				Unresolved:  []string{"price"},
+			},
+		},
+		{
+			name:       "literals only",
+			expression: `1 + 2`,
+			want:       Dependencies{},
+		},
+		{
+			name:       "syntax error",
+			expression: `(1 + 2`,
+			wantErr:    true,
+		},
+	}
+	l := New(
+		WithOperators(Operators),
+		WithPrefixOperators(PrefixOperators),
+		WithFunctions(Functions),
+		WithValues(map[string]any{
+			"jsondata": `{"rootKey1": "value1"}`,
+			"key1name": "rootKey1",
+		}),
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Dependencies(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lexpr.Dependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.Dependencies() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
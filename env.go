@@ -0,0 +1,69 @@
+package lexpr
+
+import (
+	"context"
+	"strings"
+)
+
+// Env holds variables of single evaluation. Variables of Env override Lexpr variables.
+type Env interface {
+	// Lookup returns value of variable by name.
+	Lookup(name string) (any, bool)
+}
+
+// Vars is Env of variables map. Variables are looked up by exact name and then
+// by lower case name.
+type Vars map[string]any
+
+func (v Vars) Lookup(name string) (any, bool) {
+	if value, ok := v[name]; ok {
+		return value, true
+	}
+	value, ok := v[strings.ToLower(name)]
+	return value, ok
+}
+
+// EnvFunc is Env of function that resolves variables by name.
+type EnvFunc func(name string) (any, bool)
+
+func (f EnvFunc) Lookup(name string) (any, bool) {
+	return f(name)
+}
+
+// Resolver resolves variables not found at Env and Lexpr variables, for example lazily
+// fetches them from database. Resolvers are set by WithResolver option.
+type Resolver interface {
+	// Resolve returns value of variable by name. Returns false if variable is unknown.
+	Resolve(ctx context.Context, name string) (any, bool, error)
+}
+
+// StrictResolver is Resolver that tells on compilation which names it resolves, so they are
+// known identifiers at strict mode. Values are still resolved lazily on evaluation.
+type StrictResolver interface {
+	Resolver
+	// Resolves returns true if variable with name is resolved by Resolve.
+	Resolves(name string) bool
+}
+
+// ResolverFunc is Resolver of function.
+type ResolverFunc func(ctx context.Context, name string) (any, bool, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, name string) (any, bool, error) {
+	return f(ctx, name)
+}
+
+// FunctionResolver resolves functions that are not registered at Lexpr, for example provides
+// functions of plugins on demand. It is consulted on compilation for calls like `name(...)`
+// and namespaced calls like `geo.distance(...)` with full dotted name. Resolvers are set by
+// WithFunctionResolver option.
+type FunctionResolver interface {
+	// ResolveFunction returns function by name. Returns false if function is unknown.
+	ResolveFunction(ctx context.Context, name string) (Function, bool, error)
+}
+
+// FunctionResolverFunc is FunctionResolver of function.
+type FunctionResolverFunc func(ctx context.Context, name string) (Function, bool, error)
+
+func (f FunctionResolverFunc) ResolveFunction(ctx context.Context, name string) (Function, bool, error) {
+	return f(ctx, name)
+}
@@ -0,0 +1,354 @@
+package lexpr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLexpr_EvalEnv(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		env        Env
+		want       any
+	}{
+		{
+			name:       "globals without env",
+			expression: `rate * 2`,
+			env:        nil,
+			want:       20,
+		},
+		{
+			name:       "env overrides globals",
+			expression: `rate * count`,
+			env:        Vars{"rate": 3, "count": 2},
+			want:       6,
+		},
+		{
+			name:       "lower case name",
+			expression: `Count + rate`,
+			env:        Vars{"count": 1},
+			want:       11,
+		},
+		{
+			name:       "resolver",
+			expression: `len(userName) + rate`,
+			env: EnvFunc(func(name string) (any, bool) {
+				if !strings.HasPrefix(name, "user") {
+					return nil, false
+				}
+				return strings.TrimPrefix(name, "user"), true
+			}),
+			want: 14,
+		},
+		{
+			name:       "member key",
+			expression: `user.name`,
+			env:        Vars{"user": `{"name": "alice"}`, "name": "bob"},
+			want:       "alice",
+		},
+	}
+	l := New(
+		WithOperators(Operators),
+		WithPrefixOperators(PrefixOperators),
+		WithFunctions(Functions),
+		WithValues(map[string]any{"rate": 10}),
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.OneResultEnv(context.Background(), tt.expression, tt.env)
+			if err != nil {
+				t.Fatalf("Lexpr.OneResultEnv() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.OneResultEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgram_RunEnv(t *testing.T) {
+	l := New(WithDefaults())
+	p, err := l.Compile(`n * n + len(s)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := strings.Repeat("x", i)
+			got, err := p.OneResultEnv(context.Background(), Vars{"n": i, "s": s})
+			if err != nil || got != i*i+i {
+				errs <- fmt.Errorf("n = %d: got %v, err %v", i, got, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+type ctxKey struct{}
+
+func TestLexpr_Resolver(t *testing.T) {
+	errNotAvailable := errors.New("not available")
+	tests := []struct {
+		name       string
+		expression string
+		env        Env
+		want       any
+		wantCalls  []string
+		wantErr    error
+	}{
+		{
+			name:       "only referenced names",
+			expression: `price * qty`,
+			want:       30,
+			wantCalls:  []string{"price", "qty"},
+		},
+		{
+			name:       "resolved once",
+			expression: `price + price * price`,
+			want:       110,
+			wantCalls:  []string{"price"},
+		},
+		{
+			name:       "variables first",
+			expression: `rate + price`,
+			env:        Vars{"price": 1},
+			want:       11,
+			wantCalls:  []string{},
+		},
+		{
+			name:       "member key",
+			expression: `user.name`,
+			want:       "alice",
+			wantCalls:  []string{"user"},
+		},
+		{
+			name:       "chained resolver",
+			expression: `tenant`,
+			want:       "acme",
+			wantCalls:  []string{"tenant"},
+		},
+		{
+			name:       "unknown name",
+			expression: `missing`,
+			want:       nil,
+			wantCalls:  []string{"missing"},
+		},
+		{
+			name:       "resolver error",
+			expression: `1 + broken`,
+			wantCalls:  []string{"broken"},
+			wantErr:    errNotAvailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := []string{}
+			l := New(
+				WithOperators(Operators),
+				WithPrefixOperators(PrefixOperators),
+				WithValues(map[string]any{"rate": 10}),
+				WithResolver(ResolverFunc(func(ctx context.Context, name string) (any, bool, error) {
+					calls = append(calls, name)
+					switch name {
+					case "price":
+						return 10, true, nil
+					case "qty":
+						return 3, true, nil
+					case "user":
+						return `{"name": "alice"}`, true, nil
+					case "name":
+						return "bob", true, nil
+					case "broken":
+						return nil, false, errNotAvailable
+					}
+					return nil, false, nil
+				})),
+				WithResolver(ResolverFunc(func(ctx context.Context, name string) (any, bool, error) {
+					if name != "tenant" {
+						return nil, false, nil
+					}
+					return ctx.Value(ctxKey{}), true, nil
+				})),
+			)
+			ctx := context.WithValue(context.Background(), ctxKey{}, "acme")
+			got, err := l.OneResultEnv(ctx, tt.expression, tt.env)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lexpr.OneResultEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.OneResultEnv() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("resolved names = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestLexpr_FunctionResolver(t *testing.T) {
+	errNotLoaded := errors.New("plugin not loaded")
+	tests := []struct {
+		name       string
+		expression string
+		want       any
+		wantCalls  []string
+		wantErr    error
+	}{
+		{
+			name:       "resolved once",
+			expression: `double(1) + double(2)`,
+			want:       6,
+			wantCalls:  []string{"double"},
+		},
+		{
+			name:       "namespaced",
+			expression: `geo.distance(0, 0, 3, 4) * 2`,
+			want:       10.0,
+			wantCalls:  []string{"geo.distance"},
+		},
+		{
+			name:       "registered function",
+			expression: `max(1, 2)`,
+			want:       2,
+			wantCalls:  []string{},
+		},
+		{
+			name:       "method of variable",
+			expression: `customer.HasTier("gold")`,
+			want:       true,
+			wantCalls:  []string{},
+		},
+		{
+			name:       "arity of resolved function",
+			expression: `double(1, 2)`,
+			wantCalls:  []string{"double"},
+			wantErr:    ErrArity,
+		},
+		{
+			name:       "resolver error",
+			expression: `1 + geo.area(1)`,
+			wantCalls:  []string{"geo.area"},
+			wantErr:    errNotLoaded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := []string{}
+			l := New(
+				WithOperators(Operators),
+				WithPrefixOperators(PrefixOperators),
+				WithFunctions(Functions),
+				WithValues(map[string]any{"customer": testCustomer{Tier: "gold"}}),
+				WithFunctionResolver(FunctionResolverFunc(func(ctx context.Context, name string) (Function, bool, error) {
+					calls = append(calls, name)
+					switch name {
+					case "double":
+						return NewFunc(func(args []Token) (Token, error) {
+							n, _ := args[0].Number()
+							return TokenFromInt(n * 2), nil
+						}, 1, 1), true, nil
+					case "geo.distance":
+						fn, err := NewGoFunc(name, func(x1, y1, x2, y2 float64) float64 {
+							return math.Hypot(x2-x1, y2-y1)
+						})
+						return fn, err == nil, err
+					case "geo.area":
+						return Function{}, false, errNotLoaded
+					}
+					return Function{}, false, nil
+				})),
+			)
+			p, err := l.Compile(tt.expression)
+			if err == nil {
+				var got any
+				got, err = p.OneResult(context.Background(), nil)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Program.OneResult() = %v, want %v", got, tt.want)
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lexpr.Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("resolved functions = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// testStrictResolver resolves variables with names from map.
+type testStrictResolver map[string]any
+
+func (r testStrictResolver) Resolve(ctx context.Context, name string) (any, bool, error) {
+	v, ok := r[name]
+	return v, ok, nil
+}
+
+func (r testStrictResolver) Resolves(name string) bool {
+	_, ok := r[name]
+	return ok
+}
+
+func TestLexpr_StrictResolver(t *testing.T) {
+	vars := map[string]any{"user": `{"name": "alice"}`}
+	tests := []struct {
+		name       string
+		resolver   Resolver
+		expression string
+		want       any
+		wantErr    error
+	}{
+		{
+			name:       "resolved name",
+			resolver:   testStrictResolver(vars),
+			expression: `user.name`,
+			want:       "alice",
+		},
+		{
+			name:       "typo",
+			resolver:   testStrictResolver(vars),
+			expression: `usre.name`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name: "resolver without names",
+			resolver: ResolverFunc(func(ctx context.Context, name string) (any, bool, error) {
+				v, ok := vars[name]
+				return v, ok, nil
+			}),
+			expression: `user.name`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithDefaults(), WithStrictIdentifiers("name"), WithResolver(tt.resolver))
+			p, err := l.Compile(tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lexpr.Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := p.OneResult(context.Background(), nil)
+			if err != nil || got != tt.want {
+				t.Errorf("Program.OneResult() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package lexpr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sentinel errors. All errors returned by parsing and evaluation wrap one of them,
+// so they can be checked by errors.Is.
+var (
+	ErrSyntax            = errors.New("syntax error")
+	ErrUnknownOperator   = errors.New("unknown operator")
+	ErrUnknownIdentifier = errors.New("unknown identifier")
+	ErrTypeMismatch      = errors.New("type mismatch")
+	ErrDivisionByZero    = errors.New("division by zero")
+	ErrArity             = errors.New("wrong number of arguments")
+	ErrOverflow          = errors.New("integer overflow")
+	ErrPanic             = errors.New("function panicked")
+)
+
+// Error is error of expression parsing or evaluation with position of erroneous part
+// at expression.
+type Error struct {
+	Pos  int    // Start position at expression in bytes.
+	End  int    // End position at expression in bytes.
+	Expr string // Expression.
+	Err  error  // Underlying error.
+}
+
+// newError returns error with position of token at expression. Errors that already
+// have position returned as is.
+func newError(expr string, tkn Token, err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{
+		Pos:  tkn.start,
+		End:  tkn.end,
+		Expr: expr,
+		Err:  err,
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at %d:%d", e.Err.Error(), e.Pos, e.End)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Pretty returns line of expression with erroneous part underlined by carets and error message:
+//
+//	3 @ 4
+//	  ^ unknown operator: @
+func (e *Error) Pretty() string {
+	pos := clamp(e.Pos, 0, len(e.Expr))
+	end := clamp(e.End, pos, len(e.Expr))
+	lineStart := strings.LastIndexByte(e.Expr[:pos], '\n') + 1
+	lineEnd := len(e.Expr)
+	if i := strings.IndexByte(e.Expr[pos:], '\n'); i >= 0 {
+		lineEnd = pos + i
+	}
+	if end > lineEnd {
+		end = lineEnd
+	}
+	// Keep tabs to align carets with expression.
+	indent := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, e.Expr[lineStart:pos])
+	width := utf8.RuneCountInString(e.Expr[pos:end])
+	if width == 0 {
+		width = 1
+	}
+	return fmt.Sprintf("%s\n%s%s %s", e.Expr[lineStart:lineEnd], indent, strings.Repeat("^", width), e.Err.Error())
+}
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
+
+// typeError returns error for arguments of unexpected types. Unresolved identifiers
+// between arguments reported as unknown identifiers.
+func typeError(want string, tokens ...Token) error {
+	types := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t.typ == word {
+			return fmt.Errorf("%w: %s", ErrUnknownIdentifier, t.value)
+		}
+		types = append(types, t.typeName())
+	}
+	return fmt.Errorf("%w: want %s, got %s", ErrTypeMismatch, want, strings.Join(types, " and "))
+}
+
+// checkArity returns error if count of function arguments n is out of range from minArgs
+// to maxArgs. Negative maxArgs means not limited count.
+func checkArity(name string, minArgs, maxArgs, n int) error {
+	switch {
+	case maxArgs < 0 && n < minArgs:
+		return fmt.Errorf("%w: %s requires at least %d arguments, got %d", ErrArity, name, minArgs, n)
+	case maxArgs >= 0 && minArgs == maxArgs && n != minArgs:
+		return fmt.Errorf("%w: %s requires %d arguments, got %d", ErrArity, name, minArgs, n)
+	case maxArgs >= 0 && (n < minArgs || n > maxArgs):
+		return fmt.Errorf("%w: %s requires from %d to %d arguments, got %d", ErrArity, name, minArgs, maxArgs, n)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package lexpr
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestError_Pretty(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantPos    int
+		wantEnd    int
+		want       string
+	}{
+		{
+			name:       "unknown operator",
+			expression: "1 + 3 @ 4",
+			wantPos:    6,
+			wantEnd:    7,
+			want:       "1 + 3 @ 4\n      ^ unknown operator: @",
+		},
+		{
+			name:       "invalid brakets",
+			expression: "max(1, (2 + 3)",
+			wantPos:    3,
+			wantEnd:    4,
+			want:       "max(1, (2 + 3)\n   ^ syntax error: invalid brakets",
+		},
+		{
+			name:       "multiline",
+			expression: "1 +\n\tmax(2, 3)) ? 3",
+			wantPos:    14,
+			wantEnd:    15,
+			want:       "\tmax(2, 3)) ? 3\n\t         ^ syntax error: no opening braket",
+		},
+		{
+			name:       "function arity",
+			expression: "1 + max(1)",
+			wantPos:    4,
+			wantEnd:    10,
+			want:       "1 + max(1)\n    ^^^^^^ wrong number of arguments: max requires 2 arguments, got 1",
+		},
+		{
+			name:       "unknown function",
+			expression: "1 + foo(1)",
+			wantPos:    4,
+			wantEnd:    7,
+			want:       "1 + foo(1)\n    ^^^ unknown identifier: unknown function foo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithDefaults())
+			_, err := l.Compile(tt.expression)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Lexpr.Compile() error = %v, want *Error", err)
+			}
+			if e.Pos != tt.wantPos || e.End != tt.wantEnd {
+				t.Errorf("Error position = %d:%d, want %d:%d", e.Pos, e.End, tt.wantPos, tt.wantEnd)
+			}
+			if got := e.Pretty(); got != tt.want {
+				t.Errorf("Error.Pretty() = \n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError_Is(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       error
+	}{
+		{
+			name:       "syntax",
+			expression: "(1 + 2",
+			want:       ErrSyntax,
+		},
+		{
+			name:       "unknown operator",
+			expression: "1 <> 2",
+			want:       ErrUnknownOperator,
+		},
+		{
+			name:       "unknown identifier",
+			expression: "usre.name",
+			want:       ErrUnknownIdentifier,
+		},
+		{
+			name:       "unknown function",
+			expression: "foo(1)",
+			want:       ErrUnknownIdentifier,
+		},
+		{
+			name:       "missing json key",
+			expression: "user.email",
+			want:       ErrUnknownIdentifier,
+		},
+		{
+			name:       "type mismatch",
+			expression: `1 + "2"`,
+			want:       ErrTypeMismatch,
+		},
+		{
+			name:       "division by zero",
+			expression: "1 / (2 - 2)",
+			want:       ErrDivisionByZero,
+		},
+		{
+			name:       "arity",
+			expression: "max(1)",
+			want:       ErrArity,
+		},
+		{
+			name:       "missing operand",
+			expression: "2 *",
+			want:       ErrSyntax,
+		},
+		{
+			name:       "empty then branch",
+			expression: "true ? : 1",
+			want:       ErrSyntax,
+		},
+		{
+			name:       "empty else branch",
+			expression: "true ? 1 :",
+			want:       ErrSyntax,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithDefaults())
+			l.SetVariable("user", `{"name": "test"}`)
+			_, err := l.OneResult(context.Background(), tt.expression)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Lexpr.OneResult() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
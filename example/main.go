@@ -73,17 +73,17 @@ func main() {
 	log.Println("Result 4-3:", result43)
 
 	// Logic expressions
-	result51, err := l.OneResult(ctx, `jsonData.key1name == "value1"`) // = 1
+	result51, err := l.OneResult(ctx, `jsonData.key1name == "value1"`) // = true
 	if err != nil {
 		log.Fatal(err)
 	}
 	log.Println("Result 5-1:", result51)
-	result52, err := l.OneResult(ctx, `10 >= 5 || 10 <= 5`) // = 1
+	result52, err := l.OneResult(ctx, `10 >= 5 || 10 <= 5`) // = true
 	if err != nil {
 		log.Fatal(err)
 	}
 	log.Println("Result 5-2:", result52)
-	result53, err := l.OneResult(ctx, `10 >= 5 && 10 <= 5`) // = 0
+	result53, err := l.OneResult(ctx, `10 >= 5 && 10 <= 5`) // = false
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -3,24 +3,27 @@ package lexpr
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
-func (l *Lexpr) execute(ctx context.Context, tokens <-chan Token) chan Result {
+func (l *Lexpr) execute(ctx context.Context, expression string, env Env, tokens <-chan Token) chan Result {
 	out := make(chan Result)
-	stack := TokenStack{}
+	m := l.newMachine(ctx, expression, env)
 	go func() {
 		defer func() {
-			for len(stack) > 0 {
-				ret := stack.Pop()
-				switch ret.typ {
-				case str:
-					out <- Result{Value: ret.value}
-				case number:
-					out <- Result{Value: ret.ivalue}
+			defer close(out)
+			if ctx.Err() != nil {
+				// Stack of canceled evaluation is not result.
+				return
+			}
+			for _, v := range m.results() {
+				select {
+				case out <- Result{Value: v}:
+				case <-ctx.Done():
+					return
 				}
 			}
-			close(out)
 		}()
 		for {
 			select {
@@ -30,42 +33,11 @@ func (l *Lexpr) execute(ctx context.Context, tokens <-chan Token) chan Result {
 				if !ok {
 					return
 				}
-				switch tkn.typ {
-				case number:
-					stack.Push(tkn)
-				case str:
-					stack.Push(Token{
-						typ:   str,
-						value: strings.Trim(tkn.value, `"`),
-					})
-				case funct:
-					fn := l.functions[tkn.value]
-					if err := fn(&stack); err != nil {
-						out <- Result{Error: err}
-						return
-					}
-
-				case op:
-					op := l.operators[tkn.value]
-					if err := op.handler(&stack); err != nil {
-						out <- Result{Error: err}
-						return
-					}
-
-				case word:
-					variable, hasVariable := l.variables[strings.ToLower(tkn.value)]
-					if !hasVariable {
-						stack.Push(tkn)
-						continue
+				if err := m.exec(tkn); err != nil {
+					select {
+					case out <- Result{Error: err}:
+					case <-ctx.Done():
 					}
-					vtkn, ok := TokenFromAny(variable)
-					if !ok {
-						out <- Result{Error: fmt.Errorf("invalid variable value: %+v", variable)}
-						return
-					}
-					stack.Push(vtkn)
-				case tokError:
-					out <- Result{Error: fmt.Errorf(tkn.value)}
 					return
 				}
 			}
@@ -73,3 +45,237 @@ func (l *Lexpr) execute(ctx context.Context, tokens <-chan Token) chan Result {
 	}()
 	return out
 }
+
+// machine holds state of single evaluation of rpn tokens.
+type machine struct {
+	ctx   context.Context // Context of evaluation for resolvers.
+	l     *Lexpr          // Lexpr with operators, functions and variables.
+	expr  string          // Evaluated expression.
+	env   Env             // Variables of current evaluation. Overrides Lexpr variables.
+	stack TokenStack      // Evaluation stack.
+	skip  int64           // Label to skip tokens until. Zero if not skipping.
+	// resolved caches results of resolvers by name.
+	resolved map[string]resolvedValue
+}
+
+// resolvedValue is cached result of resolvers.
+type resolvedValue struct {
+	value any
+	ok    bool
+}
+
+// newMachine returns machine for single evaluation with given variables.
+func (l *Lexpr) newMachine(ctx context.Context, expression string, env Env) *machine {
+	return &machine{
+		ctx:   ctx,
+		l:     l,
+		expr:  expression,
+		env:   env,
+		stack: TokenStack{},
+	}
+}
+
+// exec executes one rpn token. Returned error has position of token at expression.
+func (m *machine) exec(tkn Token) error {
+	if err := m.step(tkn); err != nil {
+		return newError(m.expr, tkn, err)
+	}
+	return nil
+}
+
+// step executes one rpn token.
+func (m *machine) step(tkn Token) error {
+	if tkn.typ == tokError {
+		// Errors stop evaluation even at skipped branch.
+		return tkn.err
+	}
+	if m.skip != 0 {
+		if tkn.typ == label && tkn.ivalue == m.skip {
+			m.skip = 0
+		}
+		return nil
+	}
+	switch tkn.typ {
+	case jmp:
+		m.skip = tkn.ivalue
+	case cjmp:
+		t := m.stack.Pop()
+		b, ok := t.Bool()
+		if !ok {
+			return typeError("bool", t)
+		}
+		if !b {
+			m.skip = tkn.ivalue
+		}
+	case jmpf, jmpt:
+		// Condition stays at stack as result of short-circuit operator.
+		if b, ok := m.stack.Head().Bool(); ok && b == (tkn.typ == jmpt) {
+			m.skip = tkn.ivalue
+		}
+	case number, float, boolean:
+		m.stack.Push(tkn)
+	case str:
+		m.stack.Push(Token{
+			typ:   str,
+			value: strings.Trim(tkn.value, `"`),
+		})
+	case funct:
+		fn := m.l.functions[tkn.value]
+		if tkn.call.fn != nil {
+			fn = *tkn.call.fn
+		}
+		if err := checkArity(tkn.value, fn.minArgs, fn.maxArgs, int(tkn.ivalue)); err != nil {
+			return err
+		}
+		if int64(len(m.stack)) < tkn.ivalue {
+			return fmt.Errorf("%w: function %s requires %d arguments, got %d", ErrArity, tkn.value, tkn.ivalue, len(m.stack))
+		}
+		return m.callHandler(tkn.value, fn.handler, int(tkn.ivalue))
+	case op:
+		return m.callOperator(tkn.value, m.l.operators[tkn.value])
+	case lb:
+		if int64(len(m.stack)) < tkn.ivalue {
+			return fmt.Errorf("%w: array requires %d items, got %d", ErrArity, tkn.ivalue, len(m.stack))
+		}
+		split := len(m.stack) - int(tkn.ivalue)
+		items := make([]Token, tkn.ivalue)
+		copy(items, m.stack[split:])
+		m.stack = m.stack[:split]
+		m.stack.Push(TokenFromArray(items))
+	case lc:
+		if int64(len(m.stack)) < 2*tkn.ivalue {
+			return fmt.Errorf("%w: map requires %d keys and values, got %d", ErrArity, 2*tkn.ivalue, len(m.stack))
+		}
+		split := len(m.stack) - 2*int(tkn.ivalue)
+		fields := make(map[string]Token, tkn.ivalue)
+		for i := split; i < len(m.stack); i += 2 {
+			key := m.stack[i]
+			if key.typ != str && key.typ != word {
+				return typeError("string", key)
+			}
+			fields[key.value] = m.stack[i+1]
+		}
+		m.stack = m.stack[:split]
+		m.stack.Push(TokenFromMap(fields))
+	case index:
+		key := m.stack.Pop()
+		obj := m.stack.Pop()
+		t, err := member(obj, key)
+		if err != nil {
+			return err
+		}
+		m.stack.Push(t)
+	case method:
+		if int64(len(m.stack)) < tkn.ivalue+1 {
+			return fmt.Errorf("%w: method %s requires object and %d arguments, got %d", ErrArity, tkn.value, tkn.ivalue, len(m.stack))
+		}
+		split := len(m.stack) - int(tkn.ivalue)
+		args := make([]Token, tkn.ivalue)
+		copy(args, m.stack[split:])
+		m.stack = m.stack[:split]
+		results, err := callMethod(m.stack.Pop(), tkn.value, args)
+		if err != nil {
+			return err
+		}
+		for _, t := range results {
+			m.stack.Push(t)
+		}
+	case prefix:
+		return m.callOperator(tkn.value, m.l.prefixOperators[tkn.value])
+	case word:
+		variable, hasVariable, err := m.lookup(tkn.value, tkn.member)
+		if err != nil {
+			return err
+		}
+		if !hasVariable {
+			m.stack.Push(tkn)
+			return nil
+		}
+		vtkn, ok := TokenFromAny(variable)
+		switch {
+		case !ok && isNil(reflect.ValueOf(variable)):
+			return fmt.Errorf("%w: variable %s is nil", ErrUnknownIdentifier, tkn.value)
+		case !ok:
+			return fmt.Errorf("%w: invalid variable value %T", ErrTypeMismatch, variable)
+		}
+		m.stack.Push(vtkn)
+	}
+	return nil
+}
+
+// callOperator pops exactly arity operands of operator and passes them to operator handler.
+func (m *machine) callOperator(name string, o Operator) error {
+	if len(m.stack) < o.arity {
+		return fmt.Errorf("%w: operator %s requires %d operands, got %d", ErrArity, name, o.arity, len(m.stack))
+	}
+	return m.callHandler(name, o.handler, o.arity)
+}
+
+// callHandler pops n arguments and passes them to handler at own stack, so handler
+// can't reach rest of evaluation stack. Results of handler are pushed back to stack.
+// Panic of handler is returned as error.
+func (m *machine) callHandler(name string, handler func(ts *TokenStack) error, n int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %s: %v", ErrPanic, name, r)
+		}
+	}()
+	split := len(m.stack) - n
+	args := make(TokenStack, n)
+	copy(args, m.stack[split:])
+	m.stack = m.stack[:split]
+	if err := handler(&args); err != nil {
+		return err
+	}
+	for _, t := range args {
+		m.stack.Push(t)
+	}
+	return nil
+}
+
+// lookup variable by name at evaluation variables, then at Lexpr variables and then
+// by resolvers. Evaluation variables and resolvers are not consulted for member keys,
+// like `name` at `user.name`. Results of resolvers are cached for evaluation.
+func (m *machine) lookup(name string, member bool) (any, bool, error) {
+	if m.env != nil && !member {
+		if v, ok := m.env.Lookup(name); ok {
+			return v, true, nil
+		}
+	}
+	if v, ok := m.l.variables[strings.ToLower(name)]; ok {
+		return v, true, nil
+	}
+	if len(m.l.resolvers) == 0 || member {
+		return nil, false, nil
+	}
+	if r, cached := m.resolved[name]; cached {
+		return r.value, r.ok, nil
+	}
+	r := resolvedValue{}
+	for _, resolver := range m.l.resolvers {
+		v, ok, err := resolver.Resolve(m.ctx, name)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			r = resolvedValue{value: v, ok: true}
+			break
+		}
+	}
+	if m.resolved == nil {
+		m.resolved = map[string]resolvedValue{}
+	}
+	m.resolved[name] = r
+	return r.value, r.ok, nil
+}
+
+// results pops all values from stack. Top of stack goes first.
+func (m *machine) results() []any {
+	res := make([]any, 0, len(m.stack))
+	for len(m.stack) > 0 {
+		if v, ok := m.stack.Pop().goValue(); ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -3,10 +3,22 @@ package lexpr
 import "context"
 
 func (l *Lexpr) OneResult(ctx context.Context, expression string) (any, error) {
+	return l.OneResultEnv(ctx, expression, nil)
+}
+
+// OneResultEnv evaluates expression with variables of env and returns first result.
+func (l *Lexpr) OneResultEnv(ctx context.Context, expression string, env Env) (any, error) {
+	// Rest of results is not read, cancel stops evaluation.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	select {
-	case r := <-l.Eval(ctx, expression):
+	case r, ok := <-l.EvalEnv(ctx, expression, env):
+		if !ok {
+			// No results or evaluation is canceled.
+			return nil, ctx.Err()
+		}
 		return r.Value, r.Error
 	case <-ctx.Done():
-		return nil, nil
+		return nil, ctx.Err()
 	}
 }
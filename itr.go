@@ -1,26 +1,78 @@
 package lexpr
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
+
+// indexPriority is priority of indexing `a[i]`, same as member access operator.
+const indexPriority = 140
 
 func infixToRpn(ctx context.Context, tokens <-chan Token) <-chan Token {
 	out := make(chan Token)
 	stack := TokenStack{}
+	labels := int64(0)
+	prev := Token{typ: lexEOF} // Previous token.
+	// send emits token unless evaluation is canceled.
+	send := func(tkn Token) {
+		select {
+		case out <- tkn:
+		case <-ctx.Done():
+		}
+	}
+	// syntaxError emits error token with position of tkn.
+	syntaxError := func(tkn Token, msg string) {
+		send(Token{
+			typ:   tokError,
+			err:   fmt.Errorf("%w: %s", ErrSyntax, msg),
+			start: tkn.start,
+			end:   tkn.end,
+		})
+	}
+	// popOut moves token from stack to output. Short-circuit operators are followed
+	// by label of jump emitted before their right operand. Conditional expression
+	// is completed by label after its else branch.
+	popOut := func() {
+		tkn := stack.Pop()
+		switch {
+		case tkn.typ == cond:
+			syntaxError(tkn, "conditional expression without ':'")
+		case tkn.typ == colon:
+			send(Token{
+				typ:    label,
+				ivalue: tkn.ivalue,
+			})
+		case tkn.typ == funct:
+			// Count of arguments is checked here, as function without parenthesis
+			// is popped by other tokens.
+			if err := checkArity(tkn.value, tkn.call.minArgs, tkn.call.maxArgs, int(tkn.ivalue)); err != nil {
+				send(Token{
+					typ:   tokError,
+					err:   err,
+					start: tkn.start,
+					end:   tkn.end,
+				})
+				return
+			}
+			send(tkn)
+		case tkn.typ == op && tkn.jump != 0:
+			send(tkn)
+			send(Token{
+				typ:    label,
+				ivalue: tkn.ivalue,
+			})
+		default:
+			send(tkn)
+		}
+	}
 	go func() {
 		defer func() {
-			if len(stack) > 0 {
-				for {
-					if stack.Head().typ == lp {
-						out <- Token{
-							typ:   tokError,
-							value: "invalid brakets",
-						}
-						break
-					}
-					out <- stack.Pop()
-					if len(stack) == 0 {
-						break
-					}
+			for len(stack) > 0 {
+				if isOpening(stack.Head().typ) {
+					syntaxError(stack.Head(), "invalid brakets")
+					break
 				}
+				popOut()
 			}
 			close(out)
 		}()
@@ -32,47 +84,219 @@ func infixToRpn(ctx context.Context, tokens <-chan Token) <-chan Token {
 				if !ok {
 					return
 				}
+				switch {
+				case prev.typ == word && tkn.typ == lp:
+					send(Token{
+						typ:   tokError,
+						err:   fmt.Errorf("%w: unknown function %s", ErrUnknownIdentifier, prev.value),
+						start: prev.start,
+						end:   prev.end,
+					})
+					return
+				case isOperandEnd(prev.typ) && isOperandStart(tkn.typ):
+					// Operands without operator or separator between them.
+					syntaxError(tkn, "missing operator before operand")
+					return
+				}
 				switch tkn.typ {
-				case number, word, str, tokError:
-					out <- tkn
-				case funct:
+				case number, float, boolean, word, str, tokError:
+					send(tkn)
+				case funct, prefix:
 					stack.Push(tkn)
 				case sep:
-					for stack.Head().typ != lp {
-						if len(stack) == 0 {
-							out <- Token{
-								typ:   tokError,
-								value: "no arg separator or opening braket",
-							}
+					for stack.Head().typ != lp && stack.Head().typ != lb && stack.Head().typ != lc {
+						if len(stack) == 0 || stack.Head().typ == index {
+							syntaxError(tkn, "no arg separator or opening braket")
+							return
+						}
+						popOut()
+					}
+					switch {
+					case prev.typ == sep || isOpening(prev.typ):
+						syntaxError(tkn, "missing item before separator")
+						return
+					case stack.Head().typ == lp, stack.Head().typ == lb:
+						// Count separators between arguments and array items.
+						stack[len(stack)-1].ivalue++
+					case stack.Head().typ == lc:
+						// Count of map keys and values must be even after value.
+						if stack.Head().ivalue%2 == 0 {
+							syntaxError(tkn, "missing ':' after map key")
 							return
 						}
-						out <- stack.Pop()
+						stack[len(stack)-1].ivalue++
 					}
 				case op:
-					for len(stack) > 0 && (stack.Head().typ != op || (stack.Head().priority >= tkn.priority)) {
-						out <- stack.Pop()
+					for len(stack) > 0 && (stack.Head().typ == op || stack.Head().typ == prefix) && stack.Head().priority >= tkn.priority {
+						popOut()
+					}
+					if tkn.jump != 0 {
+						// Left operand is complete, so jump over right operand can be emitted.
+						labels++
+						tkn.ivalue = labels
+						send(Token{
+							typ:    tkn.jump,
+							ivalue: labels,
+							start:  tkn.start,
+							end:    tkn.end,
+						})
 					}
 					stack.Push(tkn)
+				case cond:
+					// Conditional expression has lowest priority, so condition is complete.
+					for len(stack) > 0 && (stack.Head().typ == op || stack.Head().typ == prefix) {
+						popOut()
+					}
+					labels++
+					tkn.ivalue = labels
+					send(Token{
+						typ:    cjmp,
+						ivalue: labels,
+						start:  tkn.start,
+						end:    tkn.end,
+					})
+					stack.Push(tkn)
+				case colon:
+					if prev.typ == cond {
+						syntaxError(tkn, "missing value of conditional expression")
+						return
+					}
+					for stack.Head().typ != cond && stack.Head().typ != lc {
+						if len(stack) == 0 || isOpening(stack.Head().typ) {
+							syntaxError(tkn, "':' without '?'")
+							return
+						}
+						popOut()
+					}
+					if stack.Head().typ == lc {
+						// Separator of map key and value. Count of map keys and values
+						// must be even before key.
+						if prev.typ == lc || prev.typ == sep || stack.Head().ivalue%2 == 1 {
+							syntaxError(tkn, "unexpected ':' at map")
+							return
+						}
+						stack[len(stack)-1].ivalue++
+						break
+					}
+					elseLabel := stack.Pop().ivalue
+					labels++
+					tkn.ivalue = labels
+					send(Token{
+						typ:    jmp,
+						ivalue: labels,
+						start:  tkn.start,
+						end:    tkn.end,
+					})
+					send(Token{
+						typ:    label,
+						ivalue: elseLabel,
+					})
+					stack.Push(tkn)
 				case lp:
 					stack.Push(tkn)
 				case rp:
 					for stack.Head().typ != lp {
-						if len(stack) == 0 {
-							out <- Token{
-								typ:   tokError,
-								value: "no opening braket",
-							}
+						if len(stack) == 0 || isOpening(stack.Head().typ) {
+							syntaxError(tkn, "no opening braket")
 							return
 						}
-						out <- stack.Pop()
+						popOut()
+					}
+					open := stack.Pop()
+					switch stack.Head().typ {
+					case funct, method:
+						// Count of arguments is count of separators plus one, if any.
+						if prev.typ != lp {
+							open.ivalue++
+						}
+						stack[len(stack)-1].ivalue = open.ivalue
+						stack[len(stack)-1].end = tkn.end
+						popOut()
+					default:
+						// Grouping parentheses keep their span for syntax tree.
+						send(Token{
+							typ:   paren,
+							start: open.start,
+							end:   tkn.end,
+						})
+					}
+				case lb, lc:
+					stack.Push(tkn)
+				case index, method:
+					for len(stack) > 0 && (stack.Head().typ == op || stack.Head().typ == prefix) && stack.Head().priority >= indexPriority {
+						popOut()
 					}
-					stack.Pop()
-					if stack.Head().typ == funct {
-						out <- stack.Pop()
+					stack.Push(tkn)
+				case rb:
+					for stack.Head().typ != lb && stack.Head().typ != index {
+						if len(stack) == 0 || isOpening(stack.Head().typ) {
+							syntaxError(tkn, "no opening square braket")
+							return
+						}
+						popOut()
 					}
+					open := stack.Pop()
+					open.end = tkn.end
+					switch {
+					case open.typ == index && prev.typ == index:
+						syntaxError(open, "empty index")
+						return
+					case prev.typ == sep:
+						syntaxError(tkn, "missing item after separator")
+						return
+					case open.typ == lb && prev.typ != lb:
+						// Count of items is count of separators plus one, if array not empty.
+						open.ivalue++
+					}
+					send(open)
+				case rc:
+					for stack.Head().typ != lc {
+						if len(stack) == 0 || isOpening(stack.Head().typ) {
+							syntaxError(tkn, "no opening curly braket")
+							return
+						}
+						popOut()
+					}
+					open := stack.Pop()
+					open.end = tkn.end
+					switch {
+					case prev.typ == lc:
+						// Empty map.
+					case prev.typ == sep || prev.typ == colon || open.ivalue%2 == 0:
+						syntaxError(tkn, "missing map value")
+						return
+					default:
+						// Count of pairs.
+						open.ivalue = (open.ivalue + 1) / 2
+					}
+					send(open)
 				}
+				prev = tkn
 			}
 		}
 	}()
 	return out
 }
+
+// isOperandEnd returns true if token of type completes operand.
+func isOperandEnd(typ lexType) bool {
+	switch typ {
+	case number, float, boolean, word, str, rp, rb, rc:
+		return true
+	}
+	return false
+}
+
+// isOperandStart returns true if token of type starts operand.
+func isOperandStart(typ lexType) bool {
+	switch typ {
+	case number, float, boolean, word, str, funct, prefix, lp, lb, lc:
+		return true
+	}
+	return false
+}
+
+// isOpening returns true if token type opens brackets.
+func isOpening(typ lexType) bool {
+	return typ == lp || typ == lb || typ == index || typ == lc
+}
@@ -22,6 +22,7 @@ func Test_infixToRpn(t *testing.T) {
 					{
 						typ:   funct,
 						value: "min",
+						call:  &callInfo{minArgs: 2, maxArgs: 2},
 					},
 					{
 						typ: lp,
@@ -50,6 +51,7 @@ func Test_infixToRpn(t *testing.T) {
 					{
 						typ:   funct,
 						value: "max",
+						call:  &callInfo{minArgs: 2, maxArgs: 2},
 					},
 					{
 						typ: lp,
@@ -91,8 +93,10 @@ func Test_infixToRpn(t *testing.T) {
 					ivalue: 2,
 				},
 				{
-					typ:   funct,
-					value: "min",
+					typ:    funct,
+					value:  "min",
+					ivalue: 2,
+					call:   &callInfo{minArgs: 2, maxArgs: 2},
 				},
 				{
 					typ:    number,
@@ -103,8 +107,10 @@ func Test_infixToRpn(t *testing.T) {
 					ivalue: 20,
 				},
 				{
-					typ:   funct,
-					value: "max",
+					typ:    funct,
+					value:  "max",
+					ivalue: 2,
+					call:   &callInfo{minArgs: 2, maxArgs: 2},
 				},
 				{
 					typ:       op,
@@ -126,6 +132,272 @@ func Test_infixToRpn(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "short circuit",
+			args: args{
+				in: []Token{
+					{
+						typ:   word,
+						value: "a",
+					},
+					{
+						typ:      op,
+						value:    "&&",
+						priority: 10,
+						jump:     jmpf,
+					},
+					{
+						typ:   word,
+						value: "b",
+					},
+					{
+						typ:      op,
+						value:    "||",
+						priority: 0,
+						jump:     jmpt,
+					},
+					{
+						typ:   word,
+						value: "c",
+					},
+				},
+			},
+			want: []Token{
+				{
+					typ:   word,
+					value: "a",
+				},
+				{
+					typ:    jmpf,
+					ivalue: 1,
+				},
+				{
+					typ:   word,
+					value: "b",
+				},
+				{
+					typ:      op,
+					value:    "&&",
+					ivalue:   1,
+					priority: 10,
+					jump:     jmpf,
+				},
+				{
+					typ:    label,
+					ivalue: 1,
+				},
+				{
+					typ:    jmpt,
+					ivalue: 2,
+				},
+				{
+					typ:   word,
+					value: "c",
+				},
+				{
+					typ:      op,
+					value:    "||",
+					ivalue:   2,
+					priority: 0,
+					jump:     jmpt,
+				},
+				{
+					typ:    label,
+					ivalue: 2,
+				},
+			},
+		},
+		{
+			name: "array",
+			args: args{
+				in: []Token{
+					{
+						typ: lb,
+					},
+					{
+						typ:    number,
+						ivalue: 1,
+					},
+					{
+						typ: sep,
+					},
+					{
+						typ: lb,
+					},
+					{
+						typ: rb,
+					},
+					{
+						typ: rb,
+					},
+					{
+						typ: index,
+					},
+					{
+						typ:    number,
+						ivalue: 0,
+					},
+					{
+						typ: rb,
+					},
+				},
+			},
+			want: []Token{
+				{
+					typ:    number,
+					ivalue: 1,
+				},
+				{
+					typ:    lb,
+					ivalue: 0,
+				},
+				{
+					typ:    lb,
+					ivalue: 2,
+				},
+				{
+					typ:    number,
+					ivalue: 0,
+				},
+				{
+					typ: index,
+				},
+			},
+		},
+		{
+			name: "method",
+			args: args{
+				in: []Token{
+					{
+						typ:   word,
+						value: "order",
+					},
+					{
+						typ:   method,
+						value: "item",
+					},
+					{
+						typ: lp,
+					},
+					{
+						typ:    number,
+						ivalue: 1,
+					},
+					{
+						typ: sep,
+					},
+					{
+						typ:   word,
+						value: "customer",
+					},
+					{
+						typ:   method,
+						value: "total",
+					},
+					{
+						typ: lp,
+					},
+					{
+						typ: rp,
+					},
+					{
+						typ: rp,
+					},
+				},
+			},
+			want: []Token{
+				{
+					typ:   word,
+					value: "order",
+				},
+				{
+					typ:    number,
+					ivalue: 1,
+				},
+				{
+					typ:   word,
+					value: "customer",
+				},
+				{
+					typ:    method,
+					value:  "total",
+					ivalue: 0,
+				},
+				{
+					typ:    method,
+					value:  "item",
+					ivalue: 2,
+				},
+			},
+		},
+		{
+			name: "parentheses",
+			args: args{
+				in: []Token{
+					{
+						typ:   lp,
+						start: 0,
+						end:   1,
+					},
+					{
+						typ:    number,
+						ivalue: 1,
+					},
+					{
+						typ:      op,
+						value:    "+",
+						priority: 110,
+					},
+					{
+						typ:    number,
+						ivalue: 2,
+					},
+					{
+						typ:   rp,
+						start: 6,
+						end:   7,
+					},
+					{
+						typ:      op,
+						value:    "*",
+						priority: 120,
+					},
+					{
+						typ:    number,
+						ivalue: 3,
+					},
+				},
+			},
+			want: []Token{
+				{
+					typ:    number,
+					ivalue: 1,
+				},
+				{
+					typ:    number,
+					ivalue: 2,
+				},
+				{
+					typ:      op,
+					value:    "+",
+					priority: 110,
+				},
+				{
+					typ:   paren,
+					start: 0,
+					end:   7,
+				},
+				{
+					typ:    number,
+					ivalue: 3,
+				},
+				{
+					typ:      op,
+					value:    "*",
+					priority: 120,
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -11,11 +11,13 @@ const EOF rune = -1
 
 // lex holds current scanner state.
 type lex struct {
-	input  string     // Input string.
-	start  int        // Start position of current lexem.
-	pos    int        // Pos at input string.
-	output chan lexem // Lexems channel.
-	width  int        // Width of last rune.
+	input  string          // Input string.
+	start  int             // Start position of current lexem.
+	pos    int             // Pos at input string.
+	output chan lexem      // Lexems channel.
+	ctx    context.Context // Context of scanning, it stops emitting of lexems.
+	width  int             // Width of last rune.
+	last   lexem           // Last emitted lexem.
 }
 
 // newLex returns new scanner for input string.
@@ -25,6 +27,7 @@ func newLex() *lex {
 		start:  0,
 		pos:    0,
 		output: nil,
+		ctx:    context.Background(),
 		width:  0,
 	}
 }
@@ -32,6 +35,8 @@ func newLex() *lex {
 // parse input to lexems.
 func (l *lex) parse(ctx context.Context, input string) <-chan lexem {
 	l.input = input
+	l.ctx = ctx
+	l.last = lexem{}
 	l.output = make(chan lexem)
 	go func() {
 		defer close(l.output)
@@ -46,12 +51,26 @@ func (l *lex) parse(ctx context.Context, input string) <-chan lexem {
 				l.emit(lp)
 			case l.accept(")"):
 				l.emit(rp)
+			case l.accept("["):
+				l.emit(lb)
+			case l.accept("]"):
+				l.emit(rb)
+			case l.accept("{"):
+				l.emit(lc)
+			case l.accept("}"):
+				l.emit(rc)
 			case l.accept(","):
 				l.emit(sep)
+			case l.accept("?"):
+				l.emit(cond)
+			case l.accept(":"):
+				l.emit(colon)
 			case scanNumber(l):
 				l.emit(number)
 			case scanOps(l):
 				l.emit(op)
+			case scanBool(l):
+				l.emit(boolean)
 			case scanWord(l):
 				l.emit(word)
 			case scanQuotedString(l, `"`):
@@ -59,6 +78,7 @@ func (l *lex) parse(ctx context.Context, input string) <-chan lexem {
 			case l.peek() == EOF:
 				return
 			default:
+				l.next()
 				l.emit(tokError)
 				return
 			}
@@ -69,12 +89,16 @@ func (l *lex) parse(ctx context.Context, input string) <-chan lexem {
 
 // emit current lexem to output.
 func (l *lex) emit(typ lexType) {
-	l.output <- lexem{
+	l.last = lexem{
 		Type:  typ,
 		Value: l.input[l.start:l.pos],
 		Start: l.start,
 		End:   l.pos,
 	}
+	select {
+	case l.output <- l.last:
+	case <-l.ctx.Done():
+	}
 	l.start = l.pos
 }
 
@@ -148,7 +172,7 @@ func (l *lex) acceptWhile(valid string, ignoreEscaped bool) bool {
 		ch := l.next()
 		switch {
 		case ch == EOF:
-			return false
+			return l.pos > start
 		case ch == '\\' && ignoreEscaped:
 			l.next()
 		case !strings.ContainsRune(valid, ch):
@@ -165,7 +189,7 @@ func (l *lex) acceptWhileNot(invalid string, ignoreEscaped bool) bool {
 		ch := l.next()
 		switch {
 		case ch == EOF:
-			return false
+			return l.pos > start
 		case ch == '\\' && ignoreEscaped:
 			l.next()
 		case strings.ContainsRune(invalid, ch):
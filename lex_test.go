@@ -69,6 +69,60 @@ func Test_lex_Parse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "strings and bools",
+			args: args{
+				input: `s == "" || true`,
+			},
+			want: []lexem{
+				{
+					Type:  word,
+					Value: "s",
+				}, {
+					Type:  op,
+					Value: "==",
+				}, {
+					Type:  str,
+					Value: `""`,
+				}, {
+					Type:  op,
+					Value: "||",
+				}, {
+					Type:  boolean,
+					Value: "true",
+				},
+			},
+		},
+		{
+			name: "numeric member keys",
+			args: args{
+				input: `arr.0.1 + 0.5`,
+			},
+			want: []lexem{
+				{
+					Type:  word,
+					Value: "arr",
+				}, {
+					Type:  op,
+					Value: ".",
+				}, {
+					Type:  number,
+					Value: "0",
+				}, {
+					Type:  op,
+					Value: ".",
+				}, {
+					Type:  number,
+					Value: "1",
+				}, {
+					Type:  op,
+					Value: "+",
+				}, {
+					Type:  number,
+					Value: "0.5",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -23,4 +23,24 @@ const (
 	lp
 	rp
 	sep
+	float
+	boolean
+	jmpf
+	jmpt
+	label
+	prefix
+	cond
+	colon
+	jmp
+	cjmp
+	lb
+	rb
+	index
+	array
+	lc
+	rc
+	dict
+	object
+	method
+	paren
 )
@@ -3,12 +3,23 @@ package lexpr
 import (
 	"context"
 	"strings"
+	"sync"
 )
 
+// Lexpr is safe for concurrent use. Registries are copied on write, so each evaluation
+// uses unchanged snapshot of them.
 type Lexpr struct {
-	operators map[string]Operator
-	functions map[string]func(ts *TokenStack) error
-	variables map[string]any
+	mu              sync.RWMutex // Guards publishing of registries.
+	operators       map[string]Operator
+	prefixOperators map[string]Operator
+	functions       map[string]Function
+	variables       map[string]any
+	strict          bool                           // Unknown identifiers are errors.
+	allowedKeys     map[string]bool                // Bareword keys allowed at strict mode.
+	resolvers       []Resolver                     // Resolvers of variables not found at variables.
+	funcResolvers   []FunctionResolver             // Resolvers of functions not found at functions.
+	modules         map[string]map[string]Function // Functions of modules by module name.
+	imports         map[string]bool                // Allowed modules, nil if all modules are allowed.
 }
 
 func New(opts ...Opt) *Lexpr {
@@ -19,33 +30,182 @@ func New(opts ...Opt) *Lexpr {
 	return l
 }
 
+// Clone returns new Lexpr with same operators, functions, variables and options. Changes
+// of clone doesn't affect original Lexpr and vice versa.
+func (l *Lexpr) Clone() *Lexpr {
+	return l.snapshot()
+}
+
+// snapshot returns copy of Lexpr with current registries. Registries are not changed after
+// publishing, so they are shared.
+func (l *Lexpr) snapshot() *Lexpr {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return &Lexpr{
+		operators:       l.operators,
+		prefixOperators: l.prefixOperators,
+		functions:       l.functions,
+		variables:       l.variables,
+		strict:          l.strict,
+		allowedKeys:     l.allowedKeys,
+		resolvers:       l.resolvers,
+		funcResolvers:   l.funcResolvers,
+		modules:         l.modules,
+		imports:         l.imports,
+	}
+}
+
 func (l *Lexpr) Eval(ctx context.Context, expression string) chan Result {
+	return l.EvalEnv(ctx, expression, nil)
+}
+
+// EvalEnv evaluates expression with variables of env layered over Lexpr variables.
+func (l *Lexpr) EvalEnv(ctx context.Context, expression string, env Env) chan Result {
+	s := l.snapshot()
 	lexer := newLex()
 	lexems := lexer.parse(ctx, expression)
-	tokens := l.tokenize(ctx, lexems)
-	rpnTokens := infixToRpn(ctx, tokens)
-	return l.execute(ctx, rpnTokens)
+	tokens := s.tokenize(ctx, lexems)
+	rpnTokens := checkTokens(ctx, infixToRpn(ctx, tokens))
+	return s.execute(ctx, expression, env, rpnTokens)
 }
 
+// SetFunction sets function with any count of arguments.
 func (l *Lexpr) SetFunction(name string, fn func(ts *TokenStack) error) *Lexpr {
-	l.functions[strings.ToLower(name)] = fn
+	return l.SetFunctionWithArity(name, fn, 0, -1)
+}
+
+// SetFunctionWithArity sets function with count of arguments from minArgs to maxArgs.
+// Negative maxArgs means not limited count. Calls with other count of arguments are
+// rejected at compile time.
+func (l *Lexpr) SetFunctionWithArity(name string, fn func(ts *TokenStack) error, minArgs, maxArgs int) *Lexpr {
+	return l.setFunction(name, NewStackFunc(fn, minArgs, maxArgs))
+}
+
+// SetFunc sets function fn, that gets exact arguments of call, with count of arguments
+// from minArgs to maxArgs. Negative maxArgs means not limited count.
+func (l *Lexpr) SetFunc(name string, fn Func, minArgs, maxArgs int) *Lexpr {
+	return l.setFunction(name, NewFunc(fn, minArgs, maxArgs))
+}
+
+// RegisterFunc sets Go function fn, like `func(a, b float64) float64`, as expression function.
+// Arguments of call are checked and converted to types of fn parameters, results are converted
+// back. Function can be variadic. Not nil trailing error result is returned as evaluation error.
+func (l *Lexpr) RegisterFunc(name string, fn any) error {
+	f, err := NewGoFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	l.setFunction(name, f)
+	return nil
+}
+
+// setFunction publishes copy of functions with given function.
+func (l *Lexpr) setFunction(name string, fn Function) *Lexpr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.functions = withEntry(l.functions, strings.ToLower(name), fn)
 	return l
 }
 
 func (l *Lexpr) SetOperator(name string, fn func(ts *TokenStack) error, priority int, leftAssoc bool) *Lexpr {
-	l.operators[strings.ToLower(name)] = Operator{
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.operators = withEntry(l.operators, strings.ToLower(name), Operator{
 		handler:   fn,
+		arity:     2,
 		priority:  priority,
 		leftAssoc: leftAssoc,
-	}
+	})
+	return l
+}
+
+// SetPrefixOperator sets operator that has only right operand, like unary minus.
+// Prefix operators are distinct from binary operators with same name.
+func (l *Lexpr) SetPrefixOperator(name string, fn func(ts *TokenStack) error, priority int) *Lexpr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prefixOperators = withEntry(l.prefixOperators, strings.ToLower(name), Operator{
+		handler:  fn,
+		arity:    1,
+		priority: priority,
+	})
 	return l
 }
 
 func (l *Lexpr) SetVariable(name string, value any) *Lexpr {
-	l.variables[strings.ToLower(name)] = value
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.variables = withEntry(l.variables, strings.ToLower(name), value)
 	return l
 }
 
+// SetVariables sets many variables at once. Unlike SetVariable, variables are copied once
+// for all of them.
+func (l *Lexpr) SetVariables(variables map[string]any) *Lexpr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := cloneMap(l.variables)
+	for name, value := range variables {
+		c[strings.ToLower(name)] = value
+	}
+	l.variables = c
+	return l
+}
+
+// RegisterModule sets module of functions, that are called by dotted name like `str.upper(x)`.
+// Functions of module get exact arguments of call, like functions set by SetFunc, with any
+// count of arguments. Module with same name is replaced.
+func (l *Lexpr) RegisterModule(name string, funcs map[string]Func) *Lexpr {
+	functions := make(map[string]Function, len(funcs))
+	for fname, fn := range funcs {
+		functions[fname] = NewFunc(fn, 0, -1)
+	}
+	return l.RegisterModuleFunctions(name, functions)
+}
+
+// RegisterModuleFunctions sets module of functions built by NewFunc, NewGoFunc or NewStackFunc,
+// so count of arguments of module functions is checked at compile time. Module with same name
+// is replaced.
+func (l *Lexpr) RegisterModuleFunctions(name string, functions map[string]Function) *Lexpr {
+	module := make(map[string]Function, len(functions))
+	for fname, fn := range functions {
+		module[strings.ToLower(fname)] = fn
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.modules = withEntry(l.modules, strings.ToLower(name), module)
+	return l
+}
+
+// SetImports allows calls of given modules only. Calls of other modules are compile
+// time errors. Without SetImports or WithImports all registered modules are allowed.
+func (l *Lexpr) SetImports(modules ...string) *Lexpr {
+	imports := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		imports[strings.ToLower(m)] = true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.imports = imports
+	return l
+}
+
+// cloneMap returns copy of map. Copy of nil map is empty map.
+func cloneMap[V any](m map[string]V) map[string]V {
+	c := make(map[string]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// withEntry returns copy of map with value set by key.
+func withEntry[V any](m map[string]V, key string, value V) map[string]V {
+	c := cloneMap(m)
+	c[key] = value
+	return c
+}
+
 type Result struct {
 	Value any
 	Error error
@@ -2,14 +2,19 @@ package lexpr
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
 func TestLexpr_Eval(t *testing.T) {
 	type fields struct {
 		operators map[string]Operator
-		functions map[string]func(ts *TokenStack) error
+		functions map[string]Function
 		variables map[string]any
 	}
 	type args struct {
@@ -41,7 +46,7 @@ func TestLexpr_Eval(t *testing.T) {
 				variables: map[string]any{},
 			},
 			args:    args{expression: "min(3, 2) * max(10, 20) == 40"},
-			want:    1,
+			want:    true,
 			wantErr: false,
 		},
 		{
@@ -52,7 +57,7 @@ func TestLexpr_Eval(t *testing.T) {
 				variables: map[string]any{},
 			},
 			args:    args{expression: "min(3, 2) * max(10, 20) != 40"},
-			want:    0,
+			want:    false,
 			wantErr: false,
 		},
 		{
@@ -69,9 +74,402 @@ func TestLexpr_Eval(t *testing.T) {
 			args: args{
 				expression: "len(svar) + ivar + fvar",
 			},
-			want:    448,
+			want:    448.0,
 			wantErr: false,
 		},
+		{
+			name: "float math",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"price": 10,
+					"avg":   float32(0.5),
+				},
+			},
+			args: args{
+				expression: "price * 1.5 - 10 / 4 + avg",
+			},
+			want:    13.5,
+			wantErr: false,
+		},
+		{
+			name: "division by zero",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: "1 / 0"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "bool",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"enabled": true,
+				},
+			},
+			args:    args{expression: "enabled && 3 > 2 && !false || false"},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "bool equal",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: "true == false"},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: "brackets",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: "(1 + 2) * 3 - max(1 + 1, 2 * (2 - 1)) - 1"},
+			want:    6,
+			wantErr: false,
+		},
+		{
+			name: "short circuit and",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"user": "",
+					"j":    `{}`,
+				},
+			},
+			args:    args{expression: `user != "" && j.user == "admin"`},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: "short circuit or",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"j": `{}`,
+				},
+			},
+			args:    args{expression: `1 > 0 || j.user == "admin" && j.admin`},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "no short circuit",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"j": `{}`,
+				},
+			},
+			args:    args{expression: `1 > 0 && j.user == "admin"`},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unary minus",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"x": 3,
+				},
+			},
+			args:    args{expression: "-5 + 2 * -x - -(1 + 2) + +1 - -2 ** 2"},
+			want:    -3,
+			wantErr: false,
+		},
+		{
+			name: "unary minus float",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: "max(-1.5, -2)"},
+			want:    -1.5,
+			wantErr: false,
+		},
+		{
+			name: "logic not",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: "!(1 > 2) && !!true"},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "logic not of number",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: "!1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "missing operand",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: "1 +"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "conditional",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"tier": "gold",
+				},
+			},
+			args:    args{expression: `tier == "gold" ? 0.2 : 0.05`},
+			want:    0.2,
+			wantErr: false,
+		},
+		{
+			name: "nested conditional",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"tier": "silver",
+				},
+			},
+			args:    args{expression: `1 + (tier == "gold" ? 20 : tier == "silver" ? 10 : 5) * 2`},
+			want:    21,
+			wantErr: false,
+		},
+		{
+			name: "lazy conditional",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"j": `{}`,
+				},
+			},
+			args:    args{expression: `1 > 2 ? j.missing : 3 > 2 ? "yes" : j.missing`},
+			want:    "yes",
+			wantErr: false,
+		},
+		{
+			name: "conditional without else",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: `true ? 1`},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "else without conditional",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: `1 : 2`},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "array literal",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"x": "x",
+				},
+			},
+			args:    args{expression: `[1, 2 + 3, x, [], [true]]`},
+			want:    []any{1, 5, "x", []any{}, []any{true}},
+			wantErr: false,
+		},
+		{
+			name: "array index",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"arr": []int{1, 2, 3},
+				},
+			},
+			args:    args{expression: `[[1], [2, 3]][1][0] + arr[2] * -arr[0] + len(arr)`},
+			want:    2,
+			wantErr: false,
+		},
+		{
+			name: "numeric member keys",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"arr": [][]int{{1, 2}, {3, 4}},
+				},
+			},
+			args:    args{expression: `arr.1.0 + arr.0.1`},
+			want:    5,
+			wantErr: false,
+		},
+		{
+			name: "array equal",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"arr": []any{1, "2"},
+				},
+			},
+			args:    args{expression: `arr == [1, "2"] && arr != [1, 2]`},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "json index",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"j": `{ "one" : { "four": ["five", "six", "seven"] }, "two": "three" }`,
+				},
+			},
+			args:    args{expression: `j.one["four"][1]`},
+			want:    "six",
+			wantErr: false,
+		},
+		{
+			name: "index out of range",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: `[1, 2][2]`},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "empty index",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: `[1, 2][]`},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "not closed array",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: `[1, (2]`},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "map literal",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"tier": "gold",
+				},
+			},
+			args:    args{expression: `{"tier": tier, "discount": tier == "gold" ? 0.2 : 0.05, "tags": ["a"], "empty": {}}`},
+			want:    map[string]any{"tier": "gold", "discount": 0.2, "tags": []any{"a"}, "empty": map[string]any{}},
+			wantErr: false,
+		},
+		{
+			name: "map access",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"m": map[string]any{
+						"a": map[string]int{"b": 1},
+						"c": []any{"d"},
+					},
+				},
+			},
+			args:    args{expression: `m.a.b + len(m) + len(m["c"]) + len({"x": 1}.x == 1 ? m.c : [])`},
+			want:    5,
+			wantErr: false,
+		},
+		{
+			name: "map equal",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{
+					"m": map[string]any{"a": 1},
+				},
+			},
+			args:    args{expression: `m == {"a": 1} && m != {"a": 2} && m != {"a": 1, "b": 1}`},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "missing map key",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: `{"a": 1}.b`},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "map without value",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: `{"a": 1, "b"}`},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "map without key",
+			fields: fields{
+				operators: Operators,
+				functions: Functions,
+				variables: map[string]any{},
+			},
+			args:    args{expression: `{: 1}`},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "invalid1",
 			fields: fields{
@@ -156,9 +554,10 @@ func TestLexpr_Eval(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			l := &Lexpr{
-				operators: tt.fields.operators,
-				functions: tt.fields.functions,
-				variables: tt.fields.variables,
+				operators:       tt.fields.operators,
+				prefixOperators: PrefixOperators,
+				functions:       tt.fields.functions,
+				variables:       tt.fields.variables,
 			}
 			gotCh := l.Eval(context.Background(), tt.args.expression)
 			res := <-gotCh
@@ -174,3 +573,575 @@ func TestLexpr_Eval(t *testing.T) {
 		})
 	}
 }
+
+func TestLexpr_RegisterFunc(t *testing.T) {
+	errEmpty := errors.New("empty string")
+	funcs := map[string]any{
+		"hypot": func(a, b float64) float64 {
+			return math.Sqrt(a*a + b*b)
+		},
+		"repeat": func(s string, opts ...int) (string, error) {
+			if s == "" {
+				return "", errEmpty
+			}
+			n := 1
+			for _, opt := range opts {
+				n *= opt
+			}
+			return strings.Repeat(s, n), nil
+		},
+		"join": strings.Join,
+		"keys": func(m map[string]int) int {
+			return len(m)
+		},
+		"first": func(items []string) string {
+			return items[0]
+		},
+	}
+	tests := []struct {
+		name       string
+		expression string
+		want       any
+		wantErr    error
+	}{
+		{
+			name:       "int arguments to float parameters",
+			expression: `hypot(3, 4)`,
+			want:       5.0,
+		},
+		{
+			name:       "arguments order",
+			expression: `hypot(3, 4) - hypot(0, 1)`,
+			want:       4.0,
+		},
+		{
+			name:       "variadic without optional arguments",
+			expression: `repeat("ab")`,
+			want:       "ab",
+		},
+		{
+			name:       "variadic with optional arguments",
+			expression: `repeat("ab", 2, 2)`,
+			want:       "abababab",
+		},
+		{
+			name:       "error result",
+			expression: `repeat("")`,
+			wantErr:    errEmpty,
+		},
+		{
+			name:       "slice parameter",
+			expression: `join(["a", "b"], "-")`,
+			want:       "a-b",
+		},
+		{
+			name:       "map parameter",
+			expression: `keys({"a": 1, "b": 2})`,
+			want:       2,
+		},
+		{
+			name:       "too few arguments",
+			expression: `hypot(3)`,
+			wantErr:    ErrArity,
+		},
+		{
+			name:       "too many arguments",
+			expression: `hypot(3, 4, 5)`,
+			wantErr:    ErrArity,
+		},
+		{
+			name:       "wrong argument type",
+			expression: `repeat("ab", 1.5)`,
+			wantErr:    ErrTypeMismatch,
+		},
+		{
+			name:       "unknown identifier as argument",
+			expression: `repeat(ab)`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name:       "panic",
+			expression: `first([]) + "x"`,
+			wantErr:    ErrPanic,
+		},
+	}
+	l := New(
+		WithOperators(Operators),
+		WithPrefixOperators(PrefixOperators),
+		WithFunctions(map[string]Function{}),
+		WithValues(map[string]any{}),
+	)
+	for name, fn := range funcs {
+		if err := l.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("Lexpr.RegisterFunc(%s) error = %v", name, err)
+		}
+	}
+	if err := l.RegisterFunc("bad", 42); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Lexpr.RegisterFunc(bad) error = %v, wantErr %v", err, ErrTypeMismatch)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.OneResult(context.Background(), tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Lexpr.OneResult() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if e := (*Error)(nil); err != nil && !errors.As(err, &e) {
+				t.Errorf("Lexpr.OneResult() error = %T, want *Error", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.OneResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexpr_SetFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       any
+		wantErr    error
+	}{
+		{
+			name:       "sum",
+			expression: `sum(1, 2, 3.5, 4) - sum()`,
+			want:       10.5,
+		},
+		{
+			name:       "sum of wrong types",
+			expression: `sum(1, "2")`,
+			wantErr:    ErrTypeMismatch,
+		},
+		{
+			name:       "coalesce",
+			expression: `coalesce(missing, other, "default", "last")`,
+			want:       "default",
+		},
+		{
+			name:       "coalesce without arguments",
+			expression: `coalesce()`,
+			wantErr:    ErrArity,
+		},
+		{
+			name:       "concat of wrong types",
+			expression: `concat("a", 1, true)`,
+			wantErr:    ErrTypeMismatch,
+		},
+		{
+			name:       "concat keeps rest of stack",
+			expression: `len(concat("a", "b", "c")) * 2`,
+			want:       6,
+		},
+	}
+	l := New(
+		WithOperators(Operators),
+		WithPrefixOperators(PrefixOperators),
+		WithFunctions(map[string]Function{
+			"len": Functions["len"],
+		}),
+		WithValues(map[string]any{}),
+	)
+	l.SetFunc("sum", func(args []Token) (Token, error) {
+		sum := 0.0
+		for _, arg := range args {
+			f, ok := arg.Float()
+			if !ok {
+				return Token{}, typeError("number", arg)
+			}
+			sum += f
+		}
+		return TokenFromFloat(sum), nil
+	}, 0, -1)
+	l.SetFunc("coalesce", func(args []Token) (Token, error) {
+		for _, arg := range args {
+			if _, unresolved := arg.Word(); !unresolved {
+				return arg, nil
+			}
+		}
+		return args[len(args)-1], nil
+	}, 1, -1)
+	l.SetFunc("concat", func(args []Token) (Token, error) {
+		sb := strings.Builder{}
+		for _, arg := range args {
+			s, ok := arg.String()
+			if !ok {
+				return Token{}, typeError("string", arg)
+			}
+			sb.WriteString(s)
+		}
+		return TokenFromString(sb.String()), nil
+	}, 1, -1)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.OneResult(context.Background(), tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Lexpr.OneResult() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.OneResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexpr_StrictIdentifiers(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       any
+		wantPos    int
+		wantErr    error
+	}{
+		{
+			name:       "variable and allowed keys",
+			expression: `user.name == "test" && user.roles[0] == "admin"`,
+			want:       true,
+		},
+		{
+			name:       "typo at variable",
+			expression: `usre.name == "test"`,
+			wantErr:    ErrUnknownIdentifier,
+			wantPos:    0,
+		},
+		{
+			name:       "not allowed key",
+			expression: `user.email`,
+			wantErr:    ErrUnknownIdentifier,
+			wantPos:    5,
+		},
+		{
+			name:       "allowed key as value",
+			expression: `name`,
+			wantErr:    ErrUnknownIdentifier,
+			wantPos:    0,
+		},
+		{
+			name:       "variable as key",
+			expression: `user[field]`,
+			want:       "test",
+		},
+		{
+			name:       "function",
+			expression: `len(user.name)`,
+			want:       4,
+		},
+		{
+			name:       "allowed map keys",
+			expression: `{roles: 1, name: 2}.name`,
+			want:       2,
+		},
+		{
+			name:       "not allowed map key",
+			expression: `{email: 1}`,
+			wantErr:    ErrUnknownIdentifier,
+			wantPos:    1,
+		},
+		{
+			name:       "map value is not key",
+			expression: `{name: true ? email : 1}`,
+			wantErr:    ErrUnknownIdentifier,
+			wantPos:    14,
+		},
+	}
+	l := New(
+		WithOperators(Operators),
+		WithPrefixOperators(PrefixOperators),
+		WithFunctions(Functions),
+		WithValues(map[string]any{
+			"user":  `{"name": "test", "roles": ["admin"]}`,
+			"field": "name",
+		}),
+		WithStrictIdentifiers("Name", "roles"),
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := l.Compile(tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lexpr.Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var e *Error
+				if errors.As(err, &e) && e.Pos != tt.wantPos {
+					t.Errorf("Error position = %d, want %d", e.Pos, tt.wantPos)
+				}
+				return
+			}
+			got, err := p.OneResult(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("Program.OneResult() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Program.OneResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexpr_Isolation(t *testing.T) {
+	add := func(ts *TokenStack) error {
+		t2, t1 := ts.Pop(), ts.Pop()
+		a, _ := t1.Int()
+		b, _ := t2.Int()
+		ts.Push(TokenFromInt64(a + b))
+		return nil
+	}
+	ctx := context.Background()
+	tenant1 := New(WithDefaults())
+	tenant2 := New(WithDefaults())
+	tenant1.SetFunction("add", add).SetVariable("x", 1)
+	if _, ok := Functions["add"]; ok {
+		t.Error("SetFunction changed default functions")
+	}
+	if deps, _ := tenant2.Dependencies("add(1, 2)"); len(deps.Functions) != 0 {
+		t.Error("function of one Lexpr is available at other Lexpr")
+	}
+
+	clone := tenant1.Clone()
+	clone.SetVariable("x", 2)
+	tenant1.SetVariable("y", 3)
+	if got, err := clone.OneResult(ctx, "add(x, 10)"); err != nil || got != 12 {
+		t.Errorf("clone.OneResult() = %v, %v, want 12", got, err)
+	}
+	if got, err := tenant1.OneResult(ctx, "add(x, 10)"); err != nil || got != 11 {
+		t.Errorf("tenant1.OneResult() = %v, %v, want 11", got, err)
+	}
+	if got, _ := clone.OneResult(ctx, "y"); got != nil {
+		t.Errorf("variable of original Lexpr is available at clone, got %v", got)
+	}
+}
+
+func TestLexpr_SetVariables(t *testing.T) {
+	ctx := context.Background()
+	l := New(WithDefaults()).SetVariable("rate", 2)
+	clone := l.Clone()
+	clone.SetVariables(map[string]any{"Price": 10, "count": 3, "rate": 3})
+	if got, err := clone.OneResult(ctx, "price * count * rate"); err != nil || got != 90 {
+		t.Errorf("clone.OneResult() = %v, %v, want 90", got, err)
+	}
+	if got, err := l.OneResult(ctx, "rate"); err != nil || got != 2 {
+		t.Errorf("l.OneResult() = %v, %v, want 2", got, err)
+	}
+	if got, _ := l.OneResult(ctx, "price"); got != nil {
+		t.Errorf("variable of clone is available at original Lexpr, got %v", got)
+	}
+}
+
+func TestLexpr_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	l := New(WithDefaults())
+	l.SetVariable("n", 0)
+	p, err := l.Compile("n + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			l.SetVariable("n", i)
+			l.SetFunction(fmt.Sprintf("f%d", i), Functions["len"].handler)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := l.OneResult(ctx, "n * 2"); err != nil {
+				t.Error(err)
+			}
+			if _, err := p.OneResult(ctx, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLexpr_Modules(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		imports    []string // Imports of instance, nil if all modules are allowed.
+		want       any
+		wantErr    error
+	}{
+		{
+			name:       "module function",
+			expression: `str.upper(name) == "BOB"`,
+			want:       true,
+		},
+		{
+			name:       "same names at modules",
+			expression: `str.len("abc") * 10 + arr.len([1, 2])`,
+			want:       32,
+		},
+		{
+			name:       "flat function",
+			expression: `len(name)`,
+			want:       3,
+		},
+		{
+			name:       "case insensitive",
+			expression: `STR.Upper("a")`,
+			want:       "A",
+		},
+		{
+			name:       "json member",
+			expression: `user.email`,
+			want:       "alice@example.com",
+		},
+		{
+			name:       "imported module",
+			expression: `str.upper("a")`,
+			imports:    []string{"str"},
+			want:       "A",
+		},
+		{
+			name:       "not imported module",
+			expression: `arr.len([1])`,
+			imports:    []string{"str"},
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name:       "unknown module function",
+			expression: `str.lower("A")`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name:       "go function of module",
+			expression: `num.hypot(3, 4)`,
+			want:       5.0,
+		},
+		{
+			name:       "arity of module function",
+			expression: `num.abs()`,
+			wantErr:    ErrArity,
+		},
+		{
+			name:       "panic of module function",
+			expression: `str.upper()`,
+			wantErr:    ErrPanic,
+		},
+	}
+	l := New(
+		WithOperators(Operators),
+		WithPrefixOperators(PrefixOperators),
+		WithFunctions(Functions),
+		WithValues(map[string]any{
+			"name": "bob",
+			"user": `{"email": "alice@example.com"}`,
+		}),
+	)
+	l.RegisterModule("str", map[string]Func{
+		"upper": func(args []Token) (Token, error) {
+			s, ok := args[0].String()
+			if !ok {
+				return Token{}, typeError("string", args[0])
+			}
+			return TokenFromString(strings.ToUpper(s)), nil
+		},
+		"len": func(args []Token) (Token, error) {
+			s, _ := args[0].String()
+			return TokenFromInt(len(s)), nil
+		},
+	})
+	l.RegisterModule("arr", map[string]Func{
+		"len": func(args []Token) (Token, error) {
+			items, _ := args[0].Array()
+			return TokenFromInt(len(items)), nil
+		},
+	})
+	hypot, err := NewGoFunc("hypot", math.Hypot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.RegisterModuleFunctions("num", map[string]Function{
+		"hypot": hypot,
+		"abs": NewFunc(func(args []Token) (Token, error) {
+			f, _ := args[0].Float()
+			return TokenFromFloat(math.Abs(f)), nil
+		}, 1, 1),
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := l.Clone()
+			if tt.imports != nil {
+				l.SetImports(tt.imports...)
+			}
+			got, err := l.OneResult(context.Background(), tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lexpr.OneResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.OneResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexpr_IntegerOverflow(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       any
+		wantErr    error
+	}{
+		{
+			name:       "power by squaring",
+			expression: `2 ** 62 + 3 ** 39`,
+			want:       4611686018427387904 + 4052555153018976267,
+		},
+		{
+			name:       "large exponent",
+			expression: `1 ** 3000000000 + (-1) ** 3000000001`,
+			want:       0,
+		},
+		{
+			name:       "min int power",
+			expression: `(-2) ** 63`,
+			want:       math.MinInt64,
+		},
+		{
+			name:       "power overflow",
+			expression: `2 ** 3000000000`,
+			wantErr:    ErrOverflow,
+		},
+		{
+			name:       "sum overflow",
+			expression: `9223372036854775807 + 1`,
+			wantErr:    ErrOverflow,
+		},
+		{
+			name:       "difference overflow",
+			expression: `-9223372036854775807 - 2`,
+			wantErr:    ErrOverflow,
+		},
+		{
+			name:       "product overflow",
+			expression: `4294967296 * 4294967296`,
+			wantErr:    ErrOverflow,
+		},
+		{
+			name:       "division overflow",
+			expression: `(-9223372036854775807 - 1) / -1`,
+			wantErr:    ErrOverflow,
+		},
+		{
+			name:       "negation overflow",
+			expression: `-(-9223372036854775807 - 1)`,
+			wantErr:    ErrOverflow,
+		},
+	}
+	l := New(WithDefaults())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.OneResult(context.Background(), tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lexpr.OneResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.OneResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package lexpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// member returns element of obj by key. Obj can be array, map, Go object or json string.
+func member(obj, key Token) (Token, error) {
+	switch obj.typ {
+	case array:
+		if key.typ != number {
+			return Token{}, typeError("int", key)
+		}
+		if key.ivalue < 0 || int64(len(obj.items)) <= key.ivalue {
+			return Token{}, fmt.Errorf("%w: index %d out of range", ErrUnknownIdentifier, key.ivalue)
+		}
+		return obj.items[key.ivalue], nil
+	case dict:
+		if key.typ != str && key.typ != word {
+			return Token{}, typeError("string", key)
+		}
+		field, ok := obj.fields[key.value]
+		if !ok {
+			return Token{}, fmt.Errorf("%w: map key %s", ErrUnknownIdentifier, key.value)
+		}
+		return field, nil
+	case object:
+		if key.typ != str && key.typ != word {
+			return Token{}, typeError("string", key)
+		}
+		return objectField(obj, key.value)
+	case str:
+		return jsonMember(obj, key)
+	}
+	return Token{}, typeError("array, map, object or json string", obj)
+}
+
+// jsonMember returns element of json object or array by key.
+func jsonMember(obj, key Token) (Token, error) {
+	switch key.typ {
+	case str, word:
+		m := map[string]json.RawMessage{}
+		if err := json.Unmarshal([]byte(obj.value), &m); err != nil {
+			return Token{}, fmt.Errorf("%w: invalid json %s: %s", ErrTypeMismatch, obj.value, err.Error())
+		}
+		val, ok := m[key.value]
+		if !ok {
+			return Token{}, fmt.Errorf("%w: json key %s", ErrUnknownIdentifier, key.value)
+		}
+		return TokenFromString(strings.Trim(string(val), `"`)), nil
+	case number:
+		m := []json.RawMessage{}
+		if err := json.Unmarshal([]byte(obj.value), &m); err != nil {
+			return Token{}, fmt.Errorf("%w: invalid json %s: %s", ErrTypeMismatch, obj.value, err.Error())
+		}
+		if key.ivalue < 0 || int64(len(m)) <= key.ivalue {
+			return Token{}, fmt.Errorf("%w: json index %d", ErrUnknownIdentifier, key.ivalue)
+		}
+		return TokenFromString(strings.Trim(string(m[key.ivalue]), `"`)), nil
+	}
+	return Token{}, typeError("string or int", key)
+}
@@ -1,29 +1,75 @@
 package lexpr
 
+import "strings"
+
 type Opt func(*Lexpr)
 
 func WithOperators(operators map[string]Operator) Opt {
 	return func(l *Lexpr) {
-		l.operators = operators
+		l.operators = cloneMap(operators)
+	}
+}
+
+func WithPrefixOperators(operators map[string]Operator) Opt {
+	return func(l *Lexpr) {
+		l.prefixOperators = cloneMap(operators)
 	}
 }
 
-func WithFunctions(functions map[string]func(ts *TokenStack) error) Opt {
+func WithFunctions(functions map[string]Function) Opt {
 	return func(l *Lexpr) {
-		l.functions = functions
+		l.functions = cloneMap(functions)
 	}
 }
 
 func WithValues(variables map[string]any) Opt {
 	return func(l *Lexpr) {
-		l.variables = variables
+		l.variables = cloneMap(variables)
+	}
+}
+
+// WithStrictIdentifiers makes identifiers that are not Lexpr variables compile time errors.
+// Bareword keys, like `name` at `user.name` or `{name: 1}`, must be listed at keys, case
+// insensitive. Variables passed on evaluation must be set at Lexpr too, for example with
+// zero values.
+func WithStrictIdentifiers(keys ...string) Opt {
+	return func(l *Lexpr) {
+		l.strict = true
+		l.allowedKeys = make(map[string]bool, len(keys))
+		for _, k := range keys {
+			l.allowedKeys[strings.ToLower(k)] = true
+		}
+	}
+}
+
+// WithResolver adds resolver of variables. Resolvers are consulted in order of adding,
+// after evaluation Env and Lexpr variables.
+func WithResolver(r Resolver) Opt {
+	return func(l *Lexpr) {
+		l.resolvers = append(l.resolvers[:len(l.resolvers):len(l.resolvers)], r)
+	}
+}
+
+// WithImports allows calls of given modules only, see SetImports.
+func WithImports(modules ...string) Opt {
+	return func(l *Lexpr) {
+		l.SetImports(modules...)
+	}
+}
+
+// WithFunctionResolver adds resolver of functions. Resolvers are consulted in order of
+// adding for calls of functions that are not registered at Lexpr.
+func WithFunctionResolver(r FunctionResolver) Opt {
+	return func(l *Lexpr) {
+		l.funcResolvers = append(l.funcResolvers[:len(l.funcResolvers):len(l.funcResolvers)], r)
 	}
 }
 
 func WithDefaults() Opt {
 	return func(l *Lexpr) {
-		l.operators = Operators
-		l.functions = Functions
+		l.operators = cloneMap(Operators)
+		l.prefixOperators = cloneMap(PrefixOperators)
+		l.functions = cloneMap(Functions)
 		l.variables = map[string]any{}
 	}
 }
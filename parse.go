@@ -0,0 +1,180 @@
+package lexpr
+
+import (
+	"fmt"
+	"strings"
+
+	"go.neonxp.dev/lexpr/ast"
+)
+
+// Parse parses expression to syntax tree. Returns nil node for empty expression.
+// Expression must have single result.
+func (l *Lexpr) Parse(expression string) (ast.Node, error) {
+	p, err := l.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	b := &treeBuilder{
+		branches: map[int64]*ast.Conditional{},
+	}
+	for _, tkn := range p.tokens {
+		if err := b.step(tkn); err != nil {
+			return nil, newError(expression, tkn, err)
+		}
+	}
+	switch len(b.stack) {
+	case 0:
+		return nil, nil
+	case 1:
+		return b.stack[0], nil
+	}
+	extra := b.stack[1]
+	return nil, &Error{
+		Pos:  extra.Pos(),
+		End:  extra.End(),
+		Expr: expression,
+		Err:  fmt.Errorf("%w: expression has %d results, want one", ErrSyntax, len(b.stack)),
+	}
+}
+
+// treeBuilder builds syntax tree from rpn tokens.
+type treeBuilder struct {
+	stack    []ast.Node
+	conds    []*ast.Conditional         // Conditional expressions waiting for then branch.
+	branches map[int64]*ast.Conditional // Conditional expressions waiting for else branch by end label.
+}
+
+// step adds one rpn token to tree.
+func (b *treeBuilder) step(tkn Token) error {
+	switch tkn.typ {
+	case number:
+		b.push(b.literal(tkn, int(tkn.ivalue)))
+	case float:
+		b.push(b.literal(tkn, tkn.fvalue))
+	case boolean:
+		b.push(b.literal(tkn, tkn.bvalue))
+	case str:
+		b.push(b.literal(tkn, strings.Trim(tkn.value, `"`)))
+	case word:
+		b.push(&ast.Ident{NamePos: tkn.start, Name: tkn.value})
+	case op:
+		y, x := b.pop(), b.pop()
+		if x == nil || y == nil {
+			return fmt.Errorf("%w: operator %s requires 2 operands", ErrArity, tkn.value)
+		}
+		if tkn.value == "." {
+			b.push(&ast.Member{X: x, Sel: y})
+			return nil
+		}
+		b.push(&ast.Binary{X: x, OpPos: tkn.start, Op: tkn.value, Y: y})
+	case prefix:
+		x := b.pop()
+		if x == nil {
+			return fmt.Errorf("%w: operator %s requires operand", ErrArity, tkn.value)
+		}
+		b.push(&ast.Unary{OpPos: tkn.start, Op: tkn.value, X: x})
+	case funct:
+		args, err := b.popN(int(tkn.ivalue))
+		if err != nil {
+			return err
+		}
+		b.push(&ast.Call{
+			Fun:    &ast.Ident{NamePos: tkn.start, Name: tkn.value},
+			Args:   args,
+			Rparen: tkn.end - 1,
+		})
+	case method:
+		args, err := b.popN(int(tkn.ivalue) + 1)
+		if err != nil {
+			return err
+		}
+		b.push(&ast.Call{
+			Fun:    &ast.Member{X: args[0], Sel: &ast.Ident{NamePos: tkn.start, Name: tkn.value}},
+			Args:   args[1:],
+			Rparen: tkn.end - 1,
+		})
+	case paren:
+		if x := b.pop(); x != nil {
+			b.push(&ast.Paren{Lparen: tkn.start, X: x, Rparen: tkn.end - 1})
+		}
+	case index:
+		idx, x := b.pop(), b.pop()
+		if x == nil || idx == nil {
+			return fmt.Errorf("%w: index requires operand", ErrArity)
+		}
+		b.push(&ast.Index{X: x, Index: idx, Rbrack: tkn.end - 1})
+	case lb:
+		items, err := b.popN(int(tkn.ivalue))
+		if err != nil {
+			return err
+		}
+		b.push(&ast.Array{Lbrack: tkn.start, Items: items, Rbrack: tkn.end - 1})
+	case lc:
+		items, err := b.popN(2 * int(tkn.ivalue))
+		if err != nil {
+			return err
+		}
+		m := &ast.Map{Lbrace: tkn.start, Entries: make([]ast.MapEntry, tkn.ivalue), Rbrace: tkn.end - 1}
+		for i := range m.Entries {
+			m.Entries[i] = ast.MapEntry{Key: items[2*i], Value: items[2*i+1]}
+		}
+		b.push(m)
+	case cjmp:
+		cond := b.pop()
+		if cond == nil {
+			return fmt.Errorf("%w: missing condition", ErrSyntax)
+		}
+		b.conds = append(b.conds, &ast.Conditional{Cond: cond})
+	case jmp:
+		then := b.pop()
+		if then == nil || len(b.conds) == 0 {
+			return fmt.Errorf("%w: missing then branch", ErrSyntax)
+		}
+		c := b.conds[len(b.conds)-1]
+		b.conds = b.conds[:len(b.conds)-1]
+		c.Then = then
+		b.branches[tkn.ivalue] = c
+	case label:
+		// Labels of short-circuit operators has no branches.
+		if c, ok := b.branches[tkn.ivalue]; ok {
+			if c.Else = b.pop(); c.Else == nil {
+				return fmt.Errorf("%w: missing else branch", ErrSyntax)
+			}
+			b.push(c)
+		}
+	case tokError:
+		return tkn.err
+	}
+	return nil
+}
+
+// literal returns literal node with position of token.
+func (b *treeBuilder) literal(tkn Token, value any) *ast.Literal {
+	return &ast.Literal{ValuePos: tkn.start, ValueEnd: tkn.end, Value: value}
+}
+
+func (b *treeBuilder) push(n ast.Node) {
+	b.stack = append(b.stack, n)
+}
+
+// pop returns node from top of stack or nil if stack is empty.
+func (b *treeBuilder) pop() ast.Node {
+	if len(b.stack) == 0 {
+		return nil
+	}
+	n := b.stack[len(b.stack)-1]
+	b.stack = b.stack[:len(b.stack)-1]
+	return n
+}
+
+// popN returns n nodes from top of stack in order of pushing.
+func (b *treeBuilder) popN(n int) ([]ast.Node, error) {
+	if len(b.stack) < n {
+		return nil, fmt.Errorf("%w: requires %d operands, got %d", ErrArity, n, len(b.stack))
+	}
+	split := len(b.stack) - n
+	nodes := make([]ast.Node, n)
+	copy(nodes, b.stack[split:])
+	b.stack = b.stack[:split]
+	return nodes, nil
+}
@@ -0,0 +1,181 @@
+package lexpr
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.neonxp.dev/lexpr/ast"
+)
+
+func TestLexpr_Parse(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       ast.Node
+		wantErr    error
+	}{
+		{
+			name:       "binary operators",
+			expression: "1 + 2 * x",
+			want: &ast.Binary{
+				X:     &ast.Literal{ValuePos: 0, ValueEnd: 1, Value: 1},
+				OpPos: 2,
+				Op:    "+",
+				Y: &ast.Binary{
+					X:     &ast.Literal{ValuePos: 4, ValueEnd: 5, Value: 2},
+					OpPos: 6,
+					Op:    "*",
+					Y:     &ast.Ident{NamePos: 8, Name: "x"},
+				},
+			},
+		},
+		{
+			name:       "parentheses",
+			expression: "(a + b) * c",
+			want: &ast.Binary{
+				X: &ast.Paren{
+					Lparen: 0,
+					X: &ast.Binary{
+						X:     &ast.Ident{NamePos: 1, Name: "a"},
+						OpPos: 3,
+						Op:    "+",
+						Y:     &ast.Ident{NamePos: 5, Name: "b"},
+					},
+					Rparen: 6,
+				},
+				OpPos: 8,
+				Op:    "*",
+				Y:     &ast.Ident{NamePos: 10, Name: "c"},
+			},
+		},
+		{
+			name:       "prefix operator of parentheses",
+			expression: "-(1 + 2)",
+			want: &ast.Unary{
+				OpPos: 0,
+				Op:    "-",
+				X: &ast.Paren{
+					Lparen: 1,
+					X: &ast.Binary{
+						X:     &ast.Literal{ValuePos: 2, ValueEnd: 3, Value: 1},
+						OpPos: 4,
+						Op:    "+",
+						Y:     &ast.Literal{ValuePos: 6, ValueEnd: 7, Value: 2},
+					},
+					Rparen: 7,
+				},
+			},
+		},
+		{
+			name:       "member and index",
+			expression: "-a.b[0]",
+			want: &ast.Unary{
+				OpPos: 0,
+				Op:    "-",
+				X: &ast.Index{
+					X: &ast.Member{
+						X:   &ast.Ident{NamePos: 1, Name: "a"},
+						Sel: &ast.Ident{NamePos: 3, Name: "b"},
+					},
+					Index:  &ast.Literal{ValuePos: 5, ValueEnd: 6, Value: 0},
+					Rbrack: 6,
+				},
+			},
+		},
+		{
+			name:       "conditional",
+			expression: `max(1, x) > 2 ? "y" : [1.5]`,
+			want: &ast.Conditional{
+				Cond: &ast.Binary{
+					X: &ast.Call{
+						Fun: &ast.Ident{NamePos: 0, Name: "max"},
+						Args: []ast.Node{
+							&ast.Literal{ValuePos: 4, ValueEnd: 5, Value: 1},
+							&ast.Ident{NamePos: 7, Name: "x"},
+						},
+						Rparen: 8,
+					},
+					OpPos: 10,
+					Op:    ">",
+					Y:     &ast.Literal{ValuePos: 12, ValueEnd: 13, Value: 2},
+				},
+				Then: &ast.Literal{ValuePos: 16, ValueEnd: 19, Value: "y"},
+				Else: &ast.Array{
+					Lbrack: 22,
+					Items: []ast.Node{
+						&ast.Literal{ValuePos: 23, ValueEnd: 26, Value: 1.5},
+					},
+					Rbrack: 26,
+				},
+			},
+		},
+		{
+			name:       "method call",
+			expression: `user.HasRole("admin") && !ok`,
+			want: &ast.Binary{
+				X: &ast.Call{
+					Fun: &ast.Member{
+						X:   &ast.Ident{NamePos: 0, Name: "user"},
+						Sel: &ast.Ident{NamePos: 5, Name: "HasRole"},
+					},
+					Args: []ast.Node{
+						&ast.Literal{ValuePos: 13, ValueEnd: 20, Value: "admin"},
+					},
+					Rparen: 20,
+				},
+				OpPos: 22,
+				Op:    "&&",
+				Y: &ast.Unary{
+					OpPos: 25,
+					Op:    "!",
+					X:     &ast.Ident{NamePos: 26, Name: "ok"},
+				},
+			},
+		},
+		{
+			name:       "map",
+			expression: `{k: true}`,
+			want: &ast.Map{
+				Lbrace: 0,
+				Entries: []ast.MapEntry{
+					{
+						Key:   &ast.Ident{NamePos: 1, Name: "k"},
+						Value: &ast.Literal{ValuePos: 4, ValueEnd: 8, Value: true},
+					},
+				},
+				Rbrace: 8,
+			},
+		},
+		{
+			name:       "empty",
+			expression: "",
+			want:       nil,
+		},
+		{
+			name:       "many results",
+			expression: "1, 2",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "syntax error",
+			expression: "(1 + 2",
+			wantErr:    ErrSyntax,
+		},
+	}
+	l := New(WithDefaults())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Parse(tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lexpr.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.Parse() = %#v, want %#v", got, tt.want)
+			}
+			if got != nil && (got.Pos() != 0 || got.End() != len(tt.expression)) {
+				t.Errorf("Node position = %d:%d, want 0:%d", got.Pos(), got.End(), len(tt.expression))
+			}
+		})
+	}
+}
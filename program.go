@@ -0,0 +1,219 @@
+package lexpr
+
+import (
+	"context"
+	"fmt"
+)
+
+// Program is expression compiled to rpn tokens. Program can be evaluated many times
+// with different variables.
+type Program struct {
+	l      *Lexpr  // Lexpr with operators, functions and variables. Its snapshot is taken on run.
+	expr   string  // Source expression.
+	tokens []Token // Compiled rpn tokens.
+}
+
+// Compile parses and validates expression once. Returned program can be evaluated by Run.
+func (l *Lexpr) Compile(expression string) (*Program, error) {
+	// Pipeline stops on error, cancel releases goroutines of previous stages.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lexer := newLex()
+	lexems := lexer.parse(ctx, expression)
+	tokens := l.snapshot().tokenize(ctx, lexems)
+	rpnTokens := infixToRpn(ctx, tokens)
+	p := &Program{
+		l:      l,
+		expr:   expression,
+		tokens: []Token{},
+	}
+	for tkn := range rpnTokens {
+		if tkn.typ == tokError {
+			return nil, newError(expression, tkn, tkn.err)
+		}
+		p.tokens = append(p.tokens, tkn)
+	}
+	if tkn, err := checkStack(p.tokens); err != nil {
+		return nil, newError(expression, tkn, err)
+	}
+	return p, nil
+}
+
+// checkStack simulates depth of evaluation stack, so missing operands and values without
+// operator are found before evaluation. Each operator and function is expected to push one
+// result. Returns erroneous token.
+func checkStack(tokens []Token) (Token, error) {
+	c := newStackChecker()
+	for _, tkn := range tokens {
+		if bad, err := c.check(tkn); err != nil {
+			return bad, err
+		}
+	}
+	return c.finish()
+}
+
+// checkTokens validates stream of rpn tokens like checkStack. Tokens are passed through,
+// error token is emitted instead of first invalid token or after last token.
+func checkTokens(ctx context.Context, tokens <-chan Token) <-chan Token {
+	out := make(chan Token)
+	go func() {
+		defer close(out)
+		c := newStackChecker()
+		send := func(tkn Token) bool {
+			select {
+			case out <- tkn:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		sendError := func(tkn Token, err error) {
+			send(Token{
+				typ:   tokError,
+				err:   err,
+				start: tkn.start,
+				end:   tkn.end,
+			})
+		}
+		for tkn := range tokens {
+			if tkn.typ == tokError {
+				send(tkn)
+				return
+			}
+			if bad, err := c.check(tkn); err != nil {
+				sendError(bad, err)
+				return
+			}
+			if !send(tkn) {
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if bad, err := c.finish(); err != nil {
+			sendError(bad, err)
+		}
+	}()
+	return out
+}
+
+// stackChecker holds simulated evaluation stack of checkStack.
+type stackChecker struct {
+	// values holds tokens that push values to stack.
+	values []Token
+	// branches holds jumps over else branch with depth of stack before result of
+	// then branch by end label.
+	branches map[int64]branch
+}
+
+// branch is jump over else branch of conditional expression.
+type branch struct {
+	jmp   Token
+	depth int
+}
+
+func newStackChecker() *stackChecker {
+	return &stackChecker{
+		values:   []Token{},
+		branches: map[int64]branch{},
+	}
+}
+
+// check simulates evaluation of one rpn token. Returns erroneous token.
+func (c *stackChecker) check(tkn Token) (Token, error) {
+	n := 0 // Count of popped values.
+	switch tkn.typ {
+	case number, float, boolean, str, word:
+	case op:
+		n = 2
+	case prefix:
+		n = 1
+	case funct, lb:
+		n = int(tkn.ivalue)
+	case lc:
+		n = 2 * int(tkn.ivalue)
+	case method:
+		n = int(tkn.ivalue) + 1
+	case index:
+		n = 2
+	case cjmp:
+		if len(c.values) < 1 {
+			return tkn, fmt.Errorf("%w: missing condition", ErrSyntax)
+		}
+		c.values = c.values[:len(c.values)-1]
+		return Token{}, nil
+	case jmp:
+		// Else branch starts from same stack as then branch.
+		if len(c.values) < 1 {
+			return tkn, fmt.Errorf("%w: missing value of conditional expression", ErrSyntax)
+		}
+		c.values = c.values[:len(c.values)-1]
+		c.branches[tkn.ivalue] = branch{jmp: tkn, depth: len(c.values)}
+		return Token{}, nil
+	case label:
+		if b, ok := c.branches[tkn.ivalue]; ok && len(c.values) != b.depth+1 {
+			return b.jmp, fmt.Errorf("%w: missing value of conditional expression", ErrSyntax)
+		}
+		return Token{}, nil
+	default:
+		// Jumps of short-circuit operators keep stack.
+		return Token{}, nil
+	}
+	if len(c.values) < n {
+		return tkn, fmt.Errorf("%w: missing operand of %s", ErrSyntax, tkn.value)
+	}
+	c.values = append(c.values[:len(c.values)-n], tkn)
+	return Token{}, nil
+}
+
+// finish checks stack after last token. Returns erroneous token.
+func (c *stackChecker) finish() (Token, error) {
+	if len(c.values) > 1 {
+		return c.values[1], fmt.Errorf("%w: unexpected value without operator", ErrSyntax)
+	}
+	return Token{}, nil
+}
+
+// Run evaluates program synchronously. Variables from vars overrides Lexpr variables.
+// Returns all results from top of stack to bottom.
+func (p *Program) Run(ctx context.Context, vars map[string]any) ([]any, error) {
+	return p.RunEnv(ctx, Vars(vars))
+}
+
+// RunEnv evaluates program synchronously with variables of env layered over Lexpr variables.
+// Returns all results from top of stack to bottom.
+func (p *Program) RunEnv(ctx context.Context, env Env) ([]any, error) {
+	m := p.l.snapshot().newMachine(ctx, p.expr, env)
+	for _, tkn := range p.tokens {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := m.exec(tkn); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		// Last token is evaluated after deadline.
+		return nil, err
+	}
+	return m.results(), nil
+}
+
+// OneResult evaluates program and returns first result.
+func (p *Program) OneResult(ctx context.Context, vars map[string]any) (any, error) {
+	res, err := p.Run(ctx, vars)
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// OneResultEnv evaluates program with variables of env and returns first result.
+func (p *Program) OneResultEnv(ctx context.Context, env Env) (any, error) {
+	res, err := p.RunEnv(ctx, env)
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+	return res[0], nil
+}
@@ -0,0 +1,342 @@
+package lexpr
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestProgram_Run(t *testing.T) {
+	type args struct {
+		expression string
+		vars       []map[string]any
+	}
+	tests := []struct {
+		name           string
+		args           args
+		want           []any
+		wantCompileErr bool
+		wantErr        bool
+	}{
+		{
+			name: "simple math",
+			args: args{
+				expression: "2 + 2 * 2",
+				vars:       []map[string]any{nil, nil},
+			},
+			want: []any{6, 6},
+		},
+		{
+			name: "variables",
+			args: args{
+				expression: "len(s) + n",
+				vars: []map[string]any{
+					{"s": "test", "n": 1},
+					{"s": "longer", "n": 2},
+				},
+			},
+			want: []any{5, 8},
+		},
+		{
+			name: "global variables",
+			args: args{
+				expression: "g * n",
+				vars: []map[string]any{
+					{"n": 1},
+					{"n": 2},
+				},
+			},
+			want: []any{10, 20},
+		},
+		{
+			name: "unknown operator",
+			args: args{
+				expression: "3 @ 4",
+			},
+			wantCompileErr: true,
+		},
+		{
+			name: "invalid brakets",
+			args: args{
+				expression: "max(1, 2",
+			},
+			wantCompileErr: true,
+		},
+		{
+			name: "runtime error",
+			args: args{
+				expression: "a * 2",
+				vars: []map[string]any{
+					{"a": "str"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithDefaults())
+			l.SetVariable("g", 10)
+			p, err := l.Compile(tt.args.expression)
+			if (err != nil) != tt.wantCompileErr {
+				t.Errorf("Lexpr.Compile() error = %v, wantCompileErr %v", err, tt.wantCompileErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			got := []any{}
+			for _, vars := range tt.args.vars {
+				res, err := p.OneResult(context.Background(), vars)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Program.Run() error = %v, wantErr %v", err, tt.wantErr)
+					return
+				}
+				if err != nil {
+					return
+				}
+				got = append(got, res)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Program.Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexpr_FunctionArity(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       []any
+		wantErr    bool
+	}{
+		{
+			name:       "too few arguments",
+			expression: "max(1)",
+			wantErr:    true,
+		},
+		{
+			name:       "too many arguments",
+			expression: "max(1, 2, 3)",
+			wantErr:    true,
+		},
+		{
+			name:       "function without call",
+			expression: "len + 1",
+			wantErr:    true,
+		},
+		{
+			name:       "limited arguments",
+			expression: "round(1.5) + round(1.25, 1)",
+			want:       []any{3.3},
+		},
+		{
+			name:       "above limit",
+			expression: "round(1.5, 1, 2)",
+			wantErr:    true,
+		},
+		{
+			name:       "not limited arguments",
+			expression: "count() + count(1, 2, 3)",
+			want:       []any{3},
+		},
+		{
+			name:       "unchecked arity",
+			expression: "first(1, 2, 3)",
+			want:       []any{1},
+		},
+		{
+			name:       "stack function",
+			expression: "double(2)",
+			want:       []any{4},
+		},
+		{
+			name:       "stack function arity",
+			expression: "double()",
+			wantErr:    true,
+		},
+	}
+	functions := map[string]Function{
+		"double": NewStackFunc(func(ts *TokenStack) error {
+			n, _ := ts.Pop().Number()
+			ts.Push(TokenFromInt(n * 2))
+			return nil
+		}, 1, 1),
+	}
+	for name, fn := range Functions {
+		functions[name] = fn
+	}
+	l := New(
+		WithOperators(Operators),
+		WithPrefixOperators(PrefixOperators),
+		WithFunctions(functions),
+		WithValues(map[string]any{}),
+	)
+	l.SetFunctionWithArity("round", func(ts *TokenStack) error {
+		digits := int64(0)
+		if len(*ts) == 2 {
+			digits, _ = ts.Pop().Int()
+		}
+		f, _ := ts.Pop().Float()
+		p := math.Pow(10, float64(digits))
+		ts.Push(TokenFromFloat(math.Round(f*p) / p))
+		return nil
+	}, 1, 2)
+	l.SetFunctionWithArity("count", func(ts *TokenStack) error {
+		*ts = TokenStack{TokenFromInt(len(*ts))}
+		return nil
+	}, 0, -1)
+	l.SetFunction("first", func(ts *TokenStack) error {
+		*ts = (*ts)[:1]
+		return nil
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := l.Compile(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lexpr.Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrArity) {
+					t.Errorf("Lexpr.Compile() error = %v, want %v", err, ErrArity)
+				}
+				return
+			}
+			got, err := p.Run(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("Program.Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Program.Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexpr_CompileErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    error
+	}{
+		{
+			name:       "missing operand",
+			expression: "1 +",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "missing prefix operand",
+			expression: "-",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "missing then value",
+			expression: "true ? : 1",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "missing else value",
+			expression: "true ? 1 :",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "unknown operator",
+			expression: "1 => 2 + 3 + 4",
+			wantErr:    ErrUnknownOperator,
+		},
+		{
+			name:       "extra bracket",
+			expression: "(1 + 2)) + 3 + 4 + 5",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "arguments without separator",
+			expression: "max(1 2, 3)",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "items without separator",
+			expression: "[1 2]",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "value after call",
+			expression: "max(1, 2) 3",
+			wantErr:    ErrSyntax,
+		},
+		{
+			name:       "unknown function",
+			expression: "foo(1)",
+			wantErr:    ErrUnknownIdentifier,
+		},
+	}
+	l := New(WithDefaults())
+	before := runtime.NumGoroutine()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if _, err := l.Compile(tt.expression); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Lexpr.Compile() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+	// Goroutines of pipeline exit after compilation is canceled.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("goroutines = %d after failed compilations, want %d", n, before)
+	}
+}
+
+func TestLexpr_OneResultDeadline(t *testing.T) {
+	l := New(WithDefaults())
+	l.SetFunc("slow", func(args []Token) (Token, error) {
+		time.Sleep(100 * time.Millisecond)
+		return args[0], nil
+	}, 1, 1)
+	p, err := l.Compile(`slow(1) + 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		eval func(ctx context.Context) (any, error)
+	}{
+		{
+			name: "lexpr",
+			eval: func(ctx context.Context) (any, error) {
+				return l.OneResult(ctx, `slow(1) + 1`)
+			},
+		},
+		{
+			name: "program",
+			eval: func(ctx context.Context) (any, error) {
+				return p.OneResult(ctx, nil)
+			},
+		},
+		{
+			name: "program env",
+			eval: func(ctx context.Context) (any, error) {
+				return p.OneResultEnv(ctx, Vars{})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			got, err := tt.eval(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) || got != nil {
+				t.Errorf("OneResult() = %v, %v, want deadline exceeded", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package lexpr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// tokenFromValue converts Go value to token by its kind. Slices and maps converted to
+// arrays and maps, structs and pointers to structs kept as objects.
+func tokenFromValue(rv reflect.Value) (Token, bool) {
+	switch rv.Kind() {
+	case reflect.String:
+		return TokenFromString(rv.String()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return TokenFromInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return tokenFromUint(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return TokenFromFloat(rv.Float()), true
+	case reflect.Bool:
+		return TokenFromBool(rv.Bool()), true
+	case reflect.Slice, reflect.Array:
+		items := make([]Token, rv.Len())
+		for i := range items {
+			item, ok := tokenFromValue(rv.Index(i))
+			if !ok {
+				return Token{}, false
+			}
+			items[i] = item
+		}
+		return TokenFromArray(items), true
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return Token{}, false
+		}
+		fields := make(map[string]Token, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			field, ok := tokenFromValue(iter.Value())
+			if !ok {
+				return Token{}, false
+			}
+			fields[iter.Key().String()] = field
+		}
+		return TokenFromMap(fields), true
+	case reflect.Struct:
+		return Token{
+			typ:    object,
+			object: rv,
+		}, true
+	case reflect.Pointer:
+		if isObject(rv) {
+			// Keep pointer to struct as is.
+			return Token{
+				typ:    object,
+				object: rv,
+			}, true
+		}
+		if rv.IsNil() {
+			return Token{}, false
+		}
+		return tokenFromValue(rv.Elem())
+	case reflect.Interface:
+		if rv.IsNil() {
+			return Token{}, false
+		}
+		return tokenFromValue(rv.Elem())
+	}
+	return Token{}, false
+}
+
+// isObject returns true if value is struct or not nil pointer to struct.
+func isObject(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Pointer:
+		return !rv.IsNil() && rv.Elem().Kind() == reflect.Struct
+	}
+	return false
+}
+
+// isNil returns true if value is nil pointer or nil interface. Untyped nil is invalid value.
+func isNil(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// objectField returns exported field of object by name. Field name can be set by
+// `expr` or `json` tags. Without tags field is matched by name case insensitive.
+func objectField(obj Token, name string) (Token, error) {
+	rv := reflect.Indirect(obj.object)
+	idx, ok := structFields(rv.Type()).lookup(name)
+	if !ok {
+		return Token{}, fmt.Errorf("%w: field %s of %s", ErrUnknownIdentifier, name, rv.Type())
+	}
+	fv, err := rv.FieldByIndexErr(idx)
+	if err != nil {
+		// Nil pointer to embedded struct.
+		return Token{}, fmt.Errorf("%w: field %s of %s: %s", ErrUnknownIdentifier, name, rv.Type(), err.Error())
+	}
+	t, ok := tokenFromValue(fv)
+	switch {
+	case !ok && isNil(fv):
+		return Token{}, fmt.Errorf("%w: field %s of %s is nil", ErrUnknownIdentifier, name, rv.Type())
+	case !ok:
+		return Token{}, fmt.Errorf("%w: unsupported value of field %s of %s", ErrTypeMismatch, name, rv.Type())
+	}
+	return t, nil
+}
+
+// fieldIndex holds indexes of struct fields by name.
+type fieldIndex struct {
+	byName map[string][]int // Fields by tag or exact name.
+	byFold map[string][]int // Fields by lower case name.
+}
+
+// lookup field index by name.
+func (fi fieldIndex) lookup(name string) ([]int, bool) {
+	if idx, ok := fi.byName[name]; ok {
+		return idx, true
+	}
+	idx, ok := fi.byFold[strings.ToLower(name)]
+	return idx, ok
+}
+
+// fieldsCache holds fieldIndex by struct type.
+var fieldsCache sync.Map
+
+// structFields returns index of exported fields of struct type, including fields
+// of embedded structs.
+func structFields(t reflect.Type) fieldIndex {
+	if fi, ok := fieldsCache.Load(t); ok {
+		return fi.(fieldIndex)
+	}
+	fi := fieldIndex{
+		byName: map[string][]int{},
+		byFold: map[string][]int{},
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag := tagName(f.Tag, "expr"); tag != "" {
+			name = tag
+		} else if tag := tagName(f.Tag, "json"); tag != "" {
+			name = tag
+		}
+		if name == "-" {
+			continue
+		}
+		if _, exists := fi.byName[name]; !exists || len(f.Index) < len(fi.byName[name]) {
+			// Fields of outer struct shadow fields of embedded structs.
+			fi.byName[name] = f.Index
+		}
+		lower := strings.ToLower(name)
+		if _, exists := fi.byFold[lower]; !exists || len(f.Index) < len(fi.byFold[lower]) {
+			fi.byFold[lower] = f.Index
+		}
+	}
+	fieldsCache.Store(t, fi)
+	return fi
+}
+
+// tagName returns name part of struct tag.
+func tagName(tag reflect.StructTag, key string) string {
+	name, _, _ := strings.Cut(tag.Get(key), ",")
+	return name
+}
+
+// callMethod calls exported method of object with arguments. Method is matched by
+// name case insensitive, as fields.
+func callMethod(obj Token, name string, args []Token) ([]Token, error) {
+	if obj.typ != object {
+		return nil, typeError("object", obj)
+	}
+	fn, ok := objectMethod(obj.object, name)
+	if !ok {
+		return nil, fmt.Errorf("%w: method %s of %s", ErrUnknownIdentifier, name, obj.object.Type())
+	}
+	return callValue(name, fn, args)
+}
+
+// objectMethod returns method of value by name. Struct value is copied to pointer,
+// so methods with pointer receiver are available too.
+func objectMethod(rv reflect.Value, name string) (reflect.Value, bool) {
+	if rv.Kind() == reflect.Struct {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		rv = ptr
+	}
+	if fn := rv.MethodByName(name); fn.IsValid() {
+		return fn, true
+	}
+	t := rv.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		if strings.EqualFold(t.Method(i).Name, name) {
+			return rv.Method(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// safeCall calls Go function. Panic of function is returned as error.
+func safeCall(name string, fn reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %s: %v", ErrPanic, name, r)
+		}
+	}()
+	return fn.Call(in), nil
+}
+
+// funcArity returns minimal and maximal count of arguments of Go function type.
+// Maximal count is -1 for variadic function.
+func funcArity(ft reflect.Type) (int, int) {
+	if ft.IsVariadic() {
+		return ft.NumIn() - 1, -1
+	}
+	return ft.NumIn(), ft.NumIn()
+}
+
+// errorType is type of error interface.
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// callValue calls Go function with arguments converted from tokens. Not nil trailing
+// error result is returned as error. Single result is returned as token, many results
+// as array. Panic of function is returned as error.
+func callValue(name string, fn reflect.Value, args []Token) ([]Token, error) {
+	ft := fn.Type()
+	numIn := ft.NumIn()
+	minArgs, maxArgs := funcArity(ft)
+	if err := checkArity(name, minArgs, maxArgs, len(args)); err != nil {
+		return nil, err
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var typ reflect.Type
+		if ft.IsVariadic() && i >= numIn-1 {
+			typ = ft.In(numIn - 1).Elem()
+		} else {
+			typ = ft.In(i)
+		}
+		v, ok := valueFromToken(arg, typ)
+		if !ok {
+			return nil, fmt.Errorf("argument %d of %s: %w", i+1, name, typeError(typ.String(), arg))
+		}
+		in[i] = v
+	}
+	out, err := safeCall(name, fn, in)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:n-1]
+	}
+	results := make([]Token, len(out))
+	for i, v := range out {
+		t, ok := tokenFromValue(v)
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported result %s of %s", ErrTypeMismatch, v.Type(), name)
+		}
+		results[i] = t
+	}
+	if len(results) > 1 {
+		return []Token{TokenFromArray(results)}, nil
+	}
+	return results, nil
+}
+
+// valueFromToken converts token to Go value of given type. Numbers are converted
+// only if they fit to type.
+func valueFromToken(t Token, typ reflect.Type) (reflect.Value, bool) {
+	if t.typ == object {
+		rv := t.object
+		switch {
+		case rv.Type().AssignableTo(typ):
+			return rv, true
+		case rv.Kind() == reflect.Pointer && rv.Elem().Type().AssignableTo(typ):
+			return rv.Elem(), true
+		case rv.Kind() == reflect.Struct && reflect.PtrTo(rv.Type()).AssignableTo(typ):
+			ptr := reflect.New(rv.Type())
+			ptr.Elem().Set(rv)
+			return ptr, true
+		}
+		return reflect.Value{}, false
+	}
+	v := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		if t.typ != str {
+			return v, false
+		}
+		v.SetString(t.value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t.typ != number || v.OverflowInt(t.ivalue) {
+			return v, false
+		}
+		v.SetInt(t.ivalue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if t.typ != number || t.ivalue < 0 || v.OverflowUint(uint64(t.ivalue)) {
+			return v, false
+		}
+		v.SetUint(uint64(t.ivalue))
+	case reflect.Float32, reflect.Float64:
+		f, ok := t.Float()
+		if !ok {
+			return v, false
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		if t.typ != boolean {
+			return v, false
+		}
+		v.SetBool(t.bvalue)
+	case reflect.Slice:
+		if t.typ != array {
+			return v, false
+		}
+		v = reflect.MakeSlice(typ, len(t.items), len(t.items))
+		for i, item := range t.items {
+			iv, ok := valueFromToken(item, typ.Elem())
+			if !ok {
+				return v, false
+			}
+			v.Index(i).Set(iv)
+		}
+	case reflect.Map:
+		if t.typ != dict || typ.Key().Kind() != reflect.String {
+			return v, false
+		}
+		v = reflect.MakeMapWithSize(typ, len(t.fields))
+		for k, field := range t.fields {
+			fv, ok := valueFromToken(field, typ.Elem())
+			if !ok {
+				return v, false
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(typ.Key()), fv)
+		}
+	case reflect.Interface:
+		gv, ok := t.goValue()
+		if !ok || !reflect.TypeOf(gv).AssignableTo(typ) {
+			return v, false
+		}
+		v.Set(reflect.ValueOf(gv))
+	default:
+		return v, false
+	}
+	return v, true
+}
@@ -0,0 +1,243 @@
+package lexpr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testCustomer struct {
+	Name string
+	Tier string `json:"tier"`
+}
+
+type testAudit struct {
+	CreatedBy string `expr:"author"`
+}
+
+type testOrder struct {
+	*testAudit
+	ID       int64
+	Customer testCustomer
+	Manager  *testCustomer
+	Items    []testItem `json:"items,omitempty"`
+	Meta     map[string]any
+	Extra    any
+	Secret   string `json:"-"`
+	internal string
+}
+
+type testItem struct {
+	Price float64
+	Tags  []string
+}
+
+var errNoItem = errors.New("no item")
+
+func (c testCustomer) HasTier(tiers ...string) bool {
+	for _, tier := range tiers {
+		if c.Tier == tier {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *testCustomer) Greet(greeting string) string {
+	return fmt.Sprintf("%s, %s", greeting, c.Name)
+}
+
+func (o *testOrder) Total() float64 {
+	total := 0.0
+	for _, item := range o.Items {
+		total += item.Price
+	}
+	return total
+}
+
+func (o *testOrder) Item(i int) (testItem, error) {
+	if i < 0 || i >= len(o.Items) {
+		return testItem{}, errNoItem
+	}
+	return o.Items[i], nil
+}
+
+func (o *testOrder) Split() (int64, string) {
+	return o.ID, o.Customer.Name
+}
+
+func TestLexpr_Objects(t *testing.T) {
+	order := &testOrder{
+		testAudit: &testAudit{CreatedBy: "admin"},
+		ID:        42,
+		Customer:  testCustomer{Name: "John", Tier: "gold"},
+		Items: []testItem{
+			{Price: 10, Tags: []string{"a"}},
+			{Price: 5.5},
+		},
+		Meta:     map[string]any{"source": "web"},
+		Secret:   "secret",
+		internal: "internal",
+	}
+	tests := []struct {
+		name       string
+		expression string
+		want       any
+		wantErr    error
+	}{
+		{
+			name:       "nested struct",
+			expression: `order.Customer.Tier == "gold"`,
+			want:       true,
+		},
+		{
+			name:       "case insensitive name",
+			expression: `order.customer.name`,
+			want:       "John",
+		},
+		{
+			name:       "slice of structs",
+			expression: `order.items[0].price + order.Items.1.Price + len(order.items[0].tags)`,
+			want:       16.5,
+		},
+		{
+			name:       "map field",
+			expression: `order.Meta.source`,
+			want:       "web",
+		},
+		{
+			name:       "embedded struct with expr tag",
+			expression: `order.author`,
+			want:       "admin",
+		},
+		{
+			name:       "object result",
+			expression: `order.Customer`,
+			want:       testCustomer{Name: "John", Tier: "gold"},
+		},
+		{
+			name:       "ignored field",
+			expression: `order.Secret`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name:       "unexported field",
+			expression: `order.internal`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name:       "nil pointer",
+			expression: `order.Manager.Name`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name:       "nil interface",
+			expression: `order.Extra.ID`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name:       "nil object",
+			expression: `empty.ID`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithDefaults())
+			l.SetVariable("order", order)
+			l.SetVariable("empty", (*testOrder)(nil))
+			got, err := l.OneResult(context.Background(), tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Lexpr.OneResult() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.OneResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexpr_Methods(t *testing.T) {
+	order := &testOrder{
+		ID:       42,
+		Customer: testCustomer{Name: "John", Tier: "gold"},
+		Items: []testItem{
+			{Price: 10, Tags: []string{"a"}},
+			{Price: 5.5},
+		},
+	}
+	tests := []struct {
+		name       string
+		expression string
+		want       any
+		wantErr    error
+	}{
+		{
+			name:       "no arguments",
+			expression: `order.Total() * 2`,
+			want:       31.0,
+		},
+		{
+			name:       "variadic arguments",
+			expression: `order.Customer.HasTier("silver", "gold") && !order.customer.hasTier()`,
+			want:       true,
+		},
+		{
+			name:       "pointer receiver of struct value",
+			expression: `order.Customer.Greet("Hello")`,
+			want:       "Hello, John",
+		},
+		{
+			name:       "result with error",
+			expression: `order.Item(1 - 1).Price`,
+			want:       10.0,
+		},
+		{
+			name:       "error result",
+			expression: `order.Item(len(order.Items))`,
+			wantErr:    errNoItem,
+		},
+		{
+			name:       "many results",
+			expression: `order.Split()[1]`,
+			want:       "John",
+		},
+		{
+			name:       "unknown method",
+			expression: `order.Cancel()`,
+			wantErr:    ErrUnknownIdentifier,
+		},
+		{
+			name:       "wrong argument type",
+			expression: `order.Item("1")`,
+			wantErr:    ErrTypeMismatch,
+		},
+		{
+			name:       "wrong arguments count",
+			expression: `order.Item(1, 2)`,
+			wantErr:    ErrArity,
+		},
+		{
+			name:       "method of not object",
+			expression: `order.ID.Total()`,
+			wantErr:    ErrTypeMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithDefaults())
+			l.SetVariable("order", order)
+			got, err := l.OneResult(context.Background(), tt.expression)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Lexpr.OneResult() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lexpr.OneResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
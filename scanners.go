@@ -7,7 +7,7 @@ import (
 const (
 	digits = "0123456789"
 	alpha  = "qwertyuiopasdfghjklzxcvbnmQWERTYUIOPASDFGHJKLZXCVBNM"
-	chars  = "+-*/=<>@&|:!."
+	chars  = "+-*/%=<>@&|!."
 )
 
 // scanNumber simplest scanner that accepts decimal int and float.
@@ -17,8 +17,15 @@ func scanNumber(l *lex) bool {
 		// not found any digit
 		return false
 	}
-	l.accept(".")
-	l.acceptWhile(digits, false)
+	// Number after member operator is key, so `arr.0.1` is not float.
+	if l.last.Type == op && l.last.Value == "." {
+		return true
+	}
+	// Fraction part must contain digits, so `arr.1.key` is not float.
+	pos := l.pos
+	if l.accept(".") && !l.acceptWhile(digits, false) {
+		l.pos = pos
+	}
 	return !l.atStart()
 }
 
@@ -32,6 +39,20 @@ func scanWord(l *lex) bool {
 	return true
 }
 
+// scanBool returns true if next input token is `true` or `false` keyword.
+func scanBool(l *lex) bool {
+	start := l.pos
+	if !scanWord(l) {
+		return false
+	}
+	switch l.input[l.start:l.pos] {
+	case "true", "false":
+		return true
+	}
+	l.pos = start
+	return false
+}
+
 func scanOps(l *lex) bool {
 	return l.acceptWhile(chars, false)
 }
@@ -43,8 +64,11 @@ func scanQuotedString(l *lex, quote string) bool {
 		l.pos = start
 		return false
 	}
-	if l.acceptWhileNot(quote, true) {
-		l.next()
+	l.acceptWhileNot(quote, true)
+	if !l.accept(quote) {
+		// not terminated string
+		l.pos = start
+		return false
 	}
-	return !l.atStart()
+	return true
 }
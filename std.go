@@ -1,17 +1,19 @@
 package lexpr
 
 import (
-	"encoding/json"
 	"fmt"
 	"math"
+	"reflect"
 	"strconv"
-	"strings"
 )
 
 type Operator struct {
 	handler   func(ts *TokenStack) error
+	arity     int // Count of operands: 2 for binary and 1 for prefix operators.
 	priority  int
 	leftAssoc bool
+	jump      lexType                          // Jump over right operand for short-circuit evaluation.
+	check     func(args ...Type) (Type, error) // Types rule of operands for Check.
 }
 
 var Operators = map[string]Operator{
@@ -19,397 +21,568 @@ var Operators = map[string]Operator{
 		handler: func(ts *TokenStack) error {
 			t2 := ts.Pop()
 			t1 := ts.Pop()
-			switch t2.typ {
-			case str, word:
-				m := map[string]json.RawMessage{}
-				if err := json.Unmarshal([]byte(t1.value), &m); err != nil {
-					return fmt.Errorf("invalid json %s err: %s", t1.value, err.Error())
-				}
-				val, ok := m[t2.value]
-				if !ok {
-					return fmt.Errorf("invalid json key %s key: %s", t1.value, t2.value)
-				}
-				ts.Push(Token{
-					typ:   str,
-					value: strings.Trim(string(val), `"`),
-				})
-			case number:
-				m := []json.RawMessage{}
-				if err := json.Unmarshal([]byte(t1.value), &m); err != nil {
-					return fmt.Errorf("invalid json %s err: %s", t1.value, err.Error())
-				}
-				if len(m) <= t2.ivalue {
-					return fmt.Errorf("invalid json key %s key: %s", t1.value, t2.value)
-				}
-				val := m[t2.ivalue]
-				ts.Push(Token{
-					typ:   str,
-					value: strings.Trim(string(val), `"`),
-				})
-			default:
-				return fmt.Errorf("invalid json key: %+v", t2)
+			t, err := member(t1, t2)
+			if err != nil {
+				return err
 			}
+			ts.Push(t)
 			return nil
 		},
+		arity:     2,
 		priority:  140,
 		leftAssoc: false,
+		check:     memberOperatorType,
 	},
 	// Math operators
 	"**": {
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
 			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
+			t1 := ts.Pop()
+			if !t1.isNumeric() || !t2.isNumeric() {
+				return typeError("number", t1, t2)
 			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: int(math.Pow(float64(t1.ivalue), float64(t2.ivalue))),
-			})
+			if t1.typ == number && t2.typ == number && t2.ivalue >= 0 {
+				r, err := powInt(t1.ivalue, t2.ivalue)
+				if err != nil {
+					return err
+				}
+				ts.Push(TokenFromInt64(r))
+				return nil
+			}
+			f1, _ := t1.Float()
+			f2, _ := t2.Float()
+			ts.Push(TokenFromFloat(math.Pow(f1, f2)))
 			return nil
 		},
+		arity:     2,
 		priority:  130,
 		leftAssoc: true,
+		check:     mathType,
 	},
 	"*": {
-		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: t1.ivalue * t2.ivalue,
-			})
-			return nil
-		},
+		handler: mathOperator(
+			mulInt,
+			func(a, b float64) (float64, error) { return a * b, nil },
+		),
+		arity:     2,
 		priority:  120,
 		leftAssoc: false,
+		check:     mathType,
 	},
 	"/": {
-		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: t1.ivalue / t2.ivalue,
-			})
-			return nil
-		},
+		handler: mathOperator(
+			func(a, b int64) (int64, error) {
+				if b == 0 {
+					return 0, ErrDivisionByZero
+				}
+				if a == math.MinInt64 && b == -1 {
+					return 0, fmt.Errorf("%w: %d / %d", ErrOverflow, a, b)
+				}
+				return a / b, nil
+			},
+			func(a, b float64) (float64, error) {
+				if b == 0 {
+					return 0, ErrDivisionByZero
+				}
+				return a / b, nil
+			},
+		),
+		arity:     2,
 		priority:  120,
 		leftAssoc: false,
+		check:     mathType,
 	},
 	"%": {
-		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: t1.ivalue % t2.ivalue,
-			})
-			return nil
-		},
+		handler: mathOperator(
+			func(a, b int64) (int64, error) {
+				if b == 0 {
+					return 0, ErrDivisionByZero
+				}
+				return a % b, nil
+			},
+			func(a, b float64) (float64, error) {
+				if b == 0 {
+					return 0, ErrDivisionByZero
+				}
+				return math.Mod(a, b), nil
+			},
+		),
+		arity:     2,
 		priority:  120,
 		leftAssoc: false,
+		check:     mathType,
 	},
 	"+": {
+		handler: mathOperator(
+			addInt,
+			func(a, b float64) (float64, error) { return a + b, nil },
+		),
+		arity:     2,
+		priority:  110,
+		leftAssoc: false,
+		check:     mathType,
+	},
+	"-": {
+		handler: mathOperator(
+			subInt,
+			func(a, b float64) (float64, error) { return a - b, nil },
+		),
+		arity:     2,
+		priority:  110,
+		leftAssoc: false,
+		check:     mathType,
+	},
+
+	// Logic operators
+	">": {
+		handler:   compareOperator(func(c int) bool { return c > 0 }),
+		arity:     2,
+		priority:  20,
+		leftAssoc: false,
+		check:     compareType,
+	},
+	">=": {
+		handler:   compareOperator(func(c int) bool { return c >= 0 }),
+		arity:     2,
+		priority:  20,
+		leftAssoc: false,
+		check:     compareType,
+	},
+	"<": {
+		handler:   compareOperator(func(c int) bool { return c < 0 }),
+		arity:     2,
+		priority:  20,
+		leftAssoc: false,
+		check:     compareType,
+	},
+	"<=": {
+		handler:   compareOperator(func(c int) bool { return c <= 0 }),
+		arity:     2,
+		priority:  20,
+		leftAssoc: false,
+		check:     compareType,
+	},
+	"==": {
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
 			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: t1.ivalue + t2.ivalue,
-			})
+			t1 := ts.Pop()
+			ts.Push(TokenFromBool(equal(t1, t2)))
 			return nil
 		},
-		priority:  110,
+		arity:     2,
+		priority:  20,
 		leftAssoc: false,
+		check:     equalType,
 	},
-	"-": {
+	"!=": {
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
 			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: t1.ivalue - t2.ivalue,
-			})
+			t1 := ts.Pop()
+			ts.Push(TokenFromBool(!equal(t1, t2)))
 			return nil
 		},
-		priority:  110,
+		arity:     2,
+		priority:  20,
+		leftAssoc: false,
+		check:     equalType,
+	},
+	"&&": {
+		handler:   logicOperator(func(a, b bool) bool { return a && b }),
+		arity:     2,
+		priority:  10,
 		leftAssoc: false,
+		jump:      jmpf,
+		check:     logicType,
 	},
+	"||": {
+		handler:   logicOperator(func(a, b bool) bool { return a || b }),
+		arity:     2,
+		priority:  0,
+		leftAssoc: false,
+		jump:      jmpt,
+		check:     logicType,
+	},
+}
 
-	// Logic operators
-	"!": {
+var PrefixOperators = map[string]Operator{
+	"-": {
 		handler: func(ts *TokenStack) error {
 			t := ts.Pop()
-			switch ts.Pop().typ {
+			switch t.typ {
 			case number:
-				t.ivalue = ^t.ivalue
-				ts.Push(t)
+				r, err := subInt(0, t.ivalue)
+				if err != nil {
+					return err
+				}
+				ts.Push(TokenFromInt64(r))
+			case float:
+				ts.Push(TokenFromFloat(-t.fvalue))
 			default:
-				return fmt.Errorf("Argument must be number, got %+v", t)
+				return typeError("number", t)
 			}
 			return nil
 		},
-		priority:  50,
-		leftAssoc: false,
+		arity:    1,
+		priority: 125,
+		check:    numberType,
 	},
-	">": {
+	"+": {
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			r := 0
-			if t2.ivalue > t1.ivalue {
-				r = 1
+			t := ts.Pop()
+			if !t.isNumeric() {
+				return typeError("number", t)
 			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: r,
-			})
+			ts.Push(t)
 			return nil
 		},
-		priority:  20,
-		leftAssoc: false,
+		arity:    1,
+		priority: 125,
+		check:    numberType,
 	},
-	">=": {
+	"!": {
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			r := 0
-			if t2.ivalue >= t1.ivalue {
-				r = 1
+			t := ts.Pop()
+			b, ok := t.Bool()
+			if !ok {
+				return typeError("bool", t)
 			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: r,
-			})
+			ts.Push(TokenFromBool(!b))
 			return nil
 		},
-		priority:  20,
-		leftAssoc: false,
+		arity:    1,
+		priority: 125,
+		check:    logicType,
 	},
-	"<": {
+}
+
+// Function is expression function. Handler gets own stack with arguments of call, last
+// argument on top.
+type Function struct {
+	handler   func(ts *TokenStack) error
+	minArgs   int        // Minimal count of arguments.
+	maxArgs   int        // Maximal count of arguments, -1 if not limited.
+	signature *Signature // Types of arguments and result for Check, nil if unknown.
+}
+
+// NewStackFunc returns Function of handler, that gets own stack with arguments of call, with
+// count of arguments from minArgs to maxArgs. Negative maxArgs means not limited count.
+func NewStackFunc(handler func(ts *TokenStack) error, minArgs, maxArgs int) Function {
+	return Function{
+		handler: handler,
+		minArgs: minArgs,
+		maxArgs: maxArgs,
+	}
+}
+
+// Func is function that gets exact arguments of call and returns single result.
+type Func func(args []Token) (Token, error)
+
+// NewFunc returns Function of fn with count of arguments from minArgs to maxArgs.
+// Negative maxArgs means not limited count.
+func NewFunc(fn Func, minArgs, maxArgs int) Function {
+	return Function{
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
+			res, err := fn(*ts)
+			if err != nil {
+				return err
 			}
-			r := 0
-			if t2.ivalue < t1.ivalue {
-				r = 1
+			*ts = TokenStack{res}
+			return nil
+		},
+		minArgs: minArgs,
+		maxArgs: maxArgs,
+	}
+}
+
+// NewGoFunc returns Function of Go function fn, like `func(a, b float64) float64`. Arguments
+// of call are checked and converted to types of fn parameters, results are converted back.
+// Function can be variadic. Not nil trailing error result is returned as evaluation error.
+func NewGoFunc(name string, fn any) (Function, error) {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		return Function{}, fmt.Errorf("%w: function %s must be func, got %T", ErrTypeMismatch, name, fn)
+	}
+	minArgs, maxArgs := funcArity(rv.Type())
+	return Function{
+		handler: func(ts *TokenStack) error {
+			results, err := callValue(name, rv, *ts)
+			if err != nil {
+				return err
 			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: r,
-			})
+			*ts = results
 			return nil
 		},
-		priority:  20,
-		leftAssoc: false,
-	},
-	"<=": {
+		minArgs:   minArgs,
+		maxArgs:   maxArgs,
+		signature: funcSignature(rv.Type()),
+	}, nil
+}
+
+var Functions = map[string]Function{
+	"max": {
 		handler: func(ts *TokenStack) error {
 			t1 := ts.Pop()
 			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
+			c, err := compare(t1, t2)
+			if err != nil {
+				return err
 			}
-			r := 0
-			if t2.ivalue <= t1.ivalue {
-				r = 1
+			if c >= 0 {
+				ts.Push(t1)
+			} else {
+				ts.Push(t2)
 			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: r,
-			})
 			return nil
 		},
-		priority:  20,
-		leftAssoc: false,
+		minArgs:   2,
+		maxArgs:   2,
+		signature: &Signature{Params: []Type{TypeFloat, TypeFloat}, Result: TypeAny},
 	},
-	"==": {
+	"min": {
 		handler: func(ts *TokenStack) error {
 			t1 := ts.Pop()
 			t2 := ts.Pop()
-			r := 0
-			if t1.typ == number && t2.typ == number && t1.ivalue == t2.ivalue {
-				r = 1
-			} else if t1.value == t2.value {
-				r = 1
+			c, err := compare(t1, t2)
+			if err != nil {
+				return err
+			}
+			if c <= 0 {
+				ts.Push(t1)
+			} else {
+				ts.Push(t2)
 			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: r,
-			})
 			return nil
 		},
-		priority:  20,
-		leftAssoc: false,
+		minArgs:   2,
+		maxArgs:   2,
+		signature: &Signature{Params: []Type{TypeFloat, TypeFloat}, Result: TypeAny},
 	},
-	"!=": {
+	"len": {
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			r := 0
-			if t1.typ == number && t2.typ == number && t1.ivalue != t2.ivalue {
-				r = 1
-			} else if t1.value != t2.value {
-				r = 1
+			t := ts.Pop()
+			if items, ok := t.Array(); ok {
+				ts.Push(TokenFromInt(len(items)))
+				return nil
 			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: r,
-			})
+			if fields, ok := t.Map(); ok {
+				ts.Push(TokenFromInt(len(fields)))
+				return nil
+			}
+			ts.Push(TokenFromInt(len(t.value)))
 			return nil
 		},
-		priority:  20,
-		leftAssoc: false,
+		minArgs:   1,
+		maxArgs:   1,
+		signature: &Signature{Params: []Type{TypeAny}, Result: TypeInt},
 	},
-	"&&": {
+	"atoi": {
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			b1 := true
-			b2 := true
-			if t1.ivalue == 0 {
-				b1 = false
-			}
-			if t2.ivalue == 0 {
-				b2 = false
+			t := ts.Pop()
+			if t.typ != str && t.typ != word {
+				return typeError("string", t)
 			}
-			r := 0
-			if b1 && b2 {
-				r = 1
+			n, err := strconv.ParseInt(t.value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrTypeMismatch, err.Error())
 			}
-			ts.Push(Token{
-				typ:    number,
-				ivalue: r,
-			})
+			ts.Push(TokenFromInt64(n))
 			return nil
 		},
-		priority:  10,
-		leftAssoc: false,
+		minArgs:   1,
+		maxArgs:   1,
+		signature: &Signature{Params: []Type{TypeString}, Result: TypeInt},
 	},
-	"||": {
+	"itoa": {
 		handler: func(ts *TokenStack) error {
-			t1 := ts.Pop()
-			t2 := ts.Pop()
-			if t1.typ != number || t2.typ != number {
-				return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-			}
-			b1 := true
-			b2 := true
-			if t1.ivalue == 0 {
-				b1 = false
-			}
-			if t2.ivalue == 0 {
-				b2 = false
-			}
-			r := 0
-			if b1 || b2 {
-				r = 1
+			t := ts.Pop()
+			if t.typ != number {
+				return typeError("int", t)
 			}
+			s := strconv.FormatInt(t.ivalue, 10)
 			ts.Push(Token{
-				typ:    number,
-				ivalue: r,
+				typ:   str,
+				value: s,
 			})
 			return nil
 		},
-		priority:  0,
-		leftAssoc: false,
+		minArgs:   1,
+		maxArgs:   1,
+		signature: &Signature{Params: []Type{TypeInt}, Result: TypeString},
 	},
 }
 
-var Functions = map[string]func(ts *TokenStack) error{
-	"max": func(ts *TokenStack) error {
-		t1 := ts.Pop()
+// mathOperator returns handler of binary math operator. If any of operands is float,
+// both operands are promoted to float.
+func mathOperator(
+	intOp func(a, b int64) (int64, error),
+	floatOp func(a, b float64) (float64, error),
+) func(ts *TokenStack) error {
+	return func(ts *TokenStack) error {
 		t2 := ts.Pop()
-		if t1.typ != number || t2.typ != number {
-			return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
-		}
-		max := t1.ivalue
-		if t2.ivalue > max {
-			max = t2.ivalue
-		}
-		ts.Push(Token{
-			typ:    number,
-			ivalue: max,
-		})
-		return nil
-	},
-	"min": func(ts *TokenStack) error {
 		t1 := ts.Pop()
-		t2 := ts.Pop()
-		if t1.typ != number || t2.typ != number {
-			return fmt.Errorf("Both arguments must be number, got op1 = %+v, op2 = %+v", t1, t2)
+		if !t1.isNumeric() || !t2.isNumeric() {
+			return typeError("number", t1, t2)
 		}
-		min := t1.ivalue
-		if t2.ivalue < min {
-			min = t2.ivalue
+		if t1.typ == number && t2.typ == number {
+			r, err := intOp(t1.ivalue, t2.ivalue)
+			if err != nil {
+				return err
+			}
+			ts.Push(TokenFromInt64(r))
+			return nil
 		}
-		ts.Push(Token{
-			typ:    number,
-			ivalue: min,
-		})
-		return nil
-	},
-	"len": func(ts *TokenStack) error {
-		t := ts.Pop()
-		ts.Push(Token{
-			typ:    number,
-			ivalue: len(t.value),
-		})
+		f1, _ := t1.Float()
+		f2, _ := t2.Float()
+		r, err := floatOp(f1, f2)
+		if err != nil {
+			return err
+		}
+		ts.Push(TokenFromFloat(r))
 		return nil
-	},
-	"atoi": func(ts *TokenStack) error {
-		t := ts.Pop()
-		if t.typ != str && t.typ != word {
-			return fmt.Errorf("atoi requires string argument, got %+v", t)
+	}
+}
+
+// addInt returns a + b. Returns error if result overflows int64.
+func addInt(a, b int64) (int64, error) {
+	r := a + b
+	if (r > a) != (b > 0) {
+		return 0, fmt.Errorf("%w: %d + %d", ErrOverflow, a, b)
+	}
+	return r, nil
+}
+
+// subInt returns a - b. Returns error if result overflows int64.
+func subInt(a, b int64) (int64, error) {
+	r := a - b
+	if (r < a) != (b > 0) {
+		return 0, fmt.Errorf("%w: %d - %d", ErrOverflow, a, b)
+	}
+	return r, nil
+}
+
+// mulInt returns a * b. Returns error if result overflows int64.
+func mulInt(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	r := a * b
+	if r/b != a || a == -1 && b == math.MinInt64 || b == -1 && a == math.MinInt64 {
+		return 0, fmt.Errorf("%w: %d * %d", ErrOverflow, a, b)
+	}
+	return r, nil
+}
+
+// powInt returns a raised to not negative power b by squaring. Returns error if result
+// overflows int64.
+func powInt(a, b int64) (int64, error) {
+	base, exp := a, b
+	r := int64(1)
+	for exp > 0 {
+		var err error
+		if exp&1 == 1 {
+			if r, err = mulInt(r, base); err != nil {
+				return 0, fmt.Errorf("%w: %d ** %d", ErrOverflow, a, b)
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, err = mulInt(base, base); err != nil {
+				return 0, fmt.Errorf("%w: %d ** %d", ErrOverflow, a, b)
+			}
 		}
-		n, err := strconv.Atoi(t.value)
+	}
+	return r, nil
+}
+
+// compareOperator returns handler of numbers comparison operator. Result of comparison
+// passed to fn as -1, 0 or 1. Handler returns bool token.
+func compareOperator(fn func(c int) bool) func(ts *TokenStack) error {
+	return func(ts *TokenStack) error {
+		t2 := ts.Pop()
+		t1 := ts.Pop()
+		c, err := compare(t1, t2)
 		if err != nil {
 			return err
 		}
-		ts.Push(Token{
-			typ:    number,
-			ivalue: n,
-		})
+		ts.Push(TokenFromBool(fn(c)))
 		return nil
-	},
-	"itoa": func(ts *TokenStack) error {
-		t := ts.Pop()
-		if t.typ != number {
-			return fmt.Errorf("itoa requires number argument, got %+v", t)
+	}
+}
+
+// logicOperator returns handler of binary logic operator over bool operands.
+func logicOperator(fn func(a, b bool) bool) func(ts *TokenStack) error {
+	return func(ts *TokenStack) error {
+		t2 := ts.Pop()
+		t1 := ts.Pop()
+		b1, ok1 := t1.Bool()
+		b2, ok2 := t2.Bool()
+		if !ok1 || !ok2 {
+			return typeError("bool", t1, t2)
 		}
-		s := strconv.Itoa(t.ivalue)
-		ts.Push(Token{
-			typ:   str,
-			value: s,
-		})
+		ts.Push(TokenFromBool(fn(b1, b2)))
 		return nil
-	},
+	}
+}
+
+// compare numbers t1 and t2. Returns -1 if t1 < t2, 0 if t1 == t2 and 1 if t1 > t2.
+func compare(t1, t2 Token) (int, error) {
+	if !t1.isNumeric() || !t2.isNumeric() {
+		return 0, typeError("number", t1, t2)
+	}
+	if t1.typ == number && t2.typ == number {
+		switch {
+		case t1.ivalue < t2.ivalue:
+			return -1, nil
+		case t1.ivalue > t2.ivalue:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	f1, _ := t1.Float()
+	f2, _ := t2.Float()
+	switch {
+	case f1 < f2:
+		return -1, nil
+	case f1 > f2:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// equal returns true if tokens has equal values. Numbers are compared with promotion to float.
+func equal(t1, t2 Token) bool {
+	if t1.isNumeric() || t2.isNumeric() {
+		c, err := compare(t1, t2)
+		return err == nil && c == 0
+	}
+	if t1.typ == boolean || t2.typ == boolean {
+		return t1.typ == t2.typ && t1.bvalue == t2.bvalue
+	}
+	if t1.typ == array || t2.typ == array {
+		if t1.typ != t2.typ || len(t1.items) != len(t2.items) {
+			return false
+		}
+		for i := range t1.items {
+			if !equal(t1.items[i], t2.items[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if t1.typ == object || t2.typ == object {
+		return t1.typ == t2.typ && reflect.DeepEqual(t1.object.Interface(), t2.object.Interface())
+	}
+	if t1.typ == dict || t2.typ == dict {
+		if t1.typ != t2.typ || len(t1.fields) != len(t2.fields) {
+			return false
+		}
+		for k, f1 := range t1.fields {
+			f2, ok := t2.fields[k]
+			if !ok || !equal(f1, f2) {
+				return false
+			}
+		}
+		return true
+	}
+	return t1.value == t2.value
 }
@@ -1,17 +1,72 @@
 package lexpr
 
+import (
+	"math"
+	"reflect"
+)
+
 type Token struct {
 	typ       lexType
 	value     string
-	ivalue    int
+	ivalue    int64
+	fvalue    float64
+	items     []Token          // Items of array.
+	fields    map[string]Token // Fields of map.
+	object    reflect.Value    // Go struct or pointer to struct.
 	priority  int
+	jump      lexType
+	call      *callInfo // Function of funct token.
+	err       error     // Error of tokError token.
+	start     int       // Start position at expression.
+	end       int       // End position at expression.
+	bvalue    bool
 	leftAssoc bool
+	member    bool // Word is key after member access operator.
+}
+
+// callInfo holds function of funct token. Most tokens are not calls, so it is kept out of Token.
+type callInfo struct {
+	minArgs int       // Minimal count of function arguments.
+	maxArgs int       // Maximal count of function arguments, -1 if not limited.
+	fn      *Function // Function resolved by FunctionResolver or module, nil for Lexpr functions.
 }
 
+// Number returns integer value of token.
 func (t Token) Number() (int, bool) {
+	return int(t.ivalue), t.typ == number
+}
+
+// Int returns integer value of token.
+func (t Token) Int() (int64, bool) {
 	return t.ivalue, t.typ == number
 }
 
+// Float returns value of numeric token as float. Integer values are converted to float.
+func (t Token) Float() (float64, bool) {
+	switch t.typ {
+	case number:
+		return float64(t.ivalue), true
+	case float:
+		return t.fvalue, true
+	}
+	return 0, false
+}
+
+// Bool returns value of boolean token.
+func (t Token) Bool() (bool, bool) {
+	return t.bvalue, t.typ == boolean
+}
+
+// Array returns items of array token.
+func (t Token) Array() ([]Token, bool) {
+	return t.items, t.typ == array
+}
+
+// Map returns fields of map token.
+func (t Token) Map() (map[string]Token, bool) {
+	return t.fields, t.typ == dict
+}
+
 func (t Token) String() (string, bool) {
 	return t.value, t.typ == str
 }
@@ -20,42 +75,109 @@ func (t Token) Word() (string, bool) {
 	return t.value, t.typ == word
 }
 
-func TokenFromAny(variable any) (Token, bool) {
-	if s, ok := variable.(string); ok {
-		return Token{
-			typ:   str,
-			value: s,
-		}, true
-	}
-	if n, ok := variable.(int); ok {
-		return Token{
-			typ:    number,
-			ivalue: n,
-		}, true
+// typeName returns name of token type for error messages.
+func (t Token) typeName() string {
+	switch t.typ {
+	case number:
+		return "int"
+	case float:
+		return "float"
+	case str:
+		return "string"
+	case boolean:
+		return "bool"
+	case word:
+		return "identifier"
+	case array:
+		return "array"
+	case dict:
+		return "map"
+	case object:
+		return "object"
+	case lexEOF:
+		return "nothing"
 	}
-	if n, ok := variable.(float64); ok {
-		return Token{
-			typ:    number,
-			ivalue: int(n),
-		}, true
+	return "unknown"
+}
+
+// isNumeric returns true if token is integer or float number.
+func (t Token) isNumeric() bool {
+	return t.typ == number || t.typ == float
+}
+
+// goValue returns value of token as go value.
+func (t Token) goValue() (any, bool) {
+	switch t.typ {
+	case str:
+		return t.value, true
+	case number:
+		return int(t.ivalue), true
+	case float:
+		return t.fvalue, true
+	case boolean:
+		return t.bvalue, true
+	case array:
+		items := make([]any, len(t.items))
+		for i, item := range t.items {
+			items[i], _ = item.goValue()
+		}
+		return items, true
+	case dict:
+		fields := make(map[string]any, len(t.fields))
+		for k, field := range t.fields {
+			fields[k], _ = field.goValue()
+		}
+		return fields, true
+	case object:
+		return t.object.Interface(), true
 	}
-	if n, ok := variable.(float32); ok {
-		return Token{
-			typ:    number,
-			ivalue: int(n),
-		}, true
+	return nil, false
+}
+
+func TokenFromAny(variable any) (Token, bool) {
+	switch v := variable.(type) {
+	case string:
+		return TokenFromString(v), true
+	case int:
+		return TokenFromInt(v), true
+	case int8:
+		return TokenFromInt64(int64(v)), true
+	case int16:
+		return TokenFromInt64(int64(v)), true
+	case int32:
+		return TokenFromInt64(int64(v)), true
+	case int64:
+		return TokenFromInt64(v), true
+	case uint:
+		return tokenFromUint(uint64(v)), true
+	case uint8:
+		return TokenFromInt64(int64(v)), true
+	case uint16:
+		return TokenFromInt64(int64(v)), true
+	case uint32:
+		return TokenFromInt64(int64(v)), true
+	case uint64:
+		return tokenFromUint(v), true
+	case float32:
+		return TokenFromFloat(float64(v)), true
+	case float64:
+		return TokenFromFloat(v), true
+	case bool:
+		return TokenFromBool(v), true
+	case []Token:
+		return TokenFromArray(v), true
+	case map[string]Token:
+		return TokenFromMap(v), true
 	}
-	if b, ok := variable.(bool); ok {
-		n := 0
-		if b {
-			n = 1
-		}
-		return Token{
-			typ:    number,
-			ivalue: n,
-		}, true
+	return tokenFromValue(reflect.ValueOf(variable))
+}
+
+// tokenFromUint returns integer token if value fits into int64 and float token otherwise.
+func tokenFromUint(n uint64) Token {
+	if n > math.MaxInt64 {
+		return TokenFromFloat(float64(n))
 	}
-	return Token{}, false
+	return TokenFromInt64(int64(n))
 }
 
 func TokenFromWord(wordName string) Token {
@@ -73,8 +195,53 @@ func TokenFromString(s string) Token {
 }
 
 func TokenFromInt(n int) Token {
+	return TokenFromInt64(int64(n))
+}
+
+func TokenFromInt64(n int64) Token {
 	return Token{
 		typ:    number,
 		ivalue: n,
 	}
 }
+
+func TokenFromArray(items []Token) Token {
+	return Token{
+		typ:   array,
+		items: items,
+	}
+}
+
+func TokenFromMap(fields map[string]Token) Token {
+	return Token{
+		typ:    dict,
+		fields: fields,
+	}
+}
+
+// TokenFromObject returns token of Go struct or pointer to struct. Exported fields of
+// struct are accessed by dot operator.
+func TokenFromObject(v any) (Token, bool) {
+	rv := reflect.ValueOf(v)
+	if !isObject(rv) {
+		return Token{}, false
+	}
+	return Token{
+		typ:    object,
+		object: rv,
+	}, true
+}
+
+func TokenFromBool(b bool) Token {
+	return Token{
+		typ:    boolean,
+		bvalue: b,
+	}
+}
+
+func TokenFromFloat(n float64) Token {
+	return Token{
+		typ:    float,
+		fvalue: n,
+	}
+}
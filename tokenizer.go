@@ -4,90 +4,404 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 func (l *Lexpr) tokenize(ctx context.Context, lexems <-chan lexem) <-chan Token {
 	out := make(chan Token)
+	// prefixPos is true if next operator has no left operand.
+	prefixPos := true
+	// afterDot is true if previous token is member access operator.
+	afterDot := false
+	// prev is type of previous token.
+	prev := lexEOF
+	// brackets holds types of not closed brackets.
+	brackets := []lexType{}
+	// start and end of current lexem at expression.
+	start, end := 0, 0
+	emit := func(tkn Token) {
+		tkn.start, tkn.end = start, end
+		select {
+		case out <- tkn:
+		case <-ctx.Done():
+		}
+		afterDot = tkn.typ == op && tkn.value == "."
+		switch tkn.typ {
+		case op, prefix, lp, sep, cond, colon, lb, index, lc:
+			prefixPos = true
+		default:
+			prefixPos = false
+		}
+		switch tkn.typ {
+		case lp, lb, index, lc:
+			brackets = append(brackets, tkn.typ)
+		case rp, rb, rc:
+			if len(brackets) > 0 {
+				brackets = brackets[:len(brackets)-1]
+			}
+		}
+		prev = tkn.typ
+	}
+	// ahead holds lexems read before they are tokenized.
+	ahead := []lexem{}
+	// read returns next lexem from input.
+	read := func() (lexem, bool) {
+		select {
+		case <-ctx.Done():
+			return lexem{}, false
+		case lx, ok := <-lexems:
+			return lx, ok
+		}
+	}
+	// next returns next lexem, lexems read ahead go first.
+	next := func() (lexem, bool) {
+		if len(ahead) > 0 {
+			lx := ahead[0]
+			ahead = ahead[1:]
+			return lx, true
+		}
+		return read()
+	}
+	// peek returns lexem n positions ahead without consuming it.
+	peek := func(n int) (lexem, bool) {
+		for len(ahead) <= n {
+			lx, ok := read()
+			if !ok {
+				return lexem{}, false
+			}
+			ahead = append(ahead, lx)
+		}
+		return ahead[n], true
+	}
+	// isMethodCall returns true if `.` is followed by name and opening parenthesis.
+	isMethodCall := func() bool {
+		name, ok1 := peek(0)
+		paren, ok2 := peek(1)
+		return ok1 && ok2 && name.Type == word && paren.Type == lp
+	}
+	// callName returns dotted name of call that starts with given word, like `geo.distance`
+	// for `geo.distance(`, and count of lexems of name after first word. Returns false if
+	// word is not followed by opening parenthesis.
+	callName := func(first string) (string, int, bool) {
+		name := first
+		for n := 0; ; n += 2 {
+			lx, ok := peek(n)
+			switch {
+			case !ok:
+				return "", 0, false
+			case lx.Type == lp:
+				return name, n, true
+			case lx.Type == op && lx.Value == ".":
+				key, ok := peek(n + 1)
+				if !ok || key.Type != word {
+					return "", 0, false
+				}
+				name += "." + key.Value
+			default:
+				return "", 0, false
+			}
+		}
+	}
+	// isMapKey returns true if word is key of map literal, like `status` at `{status: 1}`.
+	isMapKey := func() bool {
+		if len(brackets) == 0 || brackets[len(brackets)-1] != lc || prev != lc && prev != sep {
+			return false
+		}
+		lx, ok := peek(0)
+		return ok && lx.Type == colon
+	}
+	// resolved holds functions returned by resolvers, nil for unknown names.
+	resolved := map[string]*Function{}
+	// resolveCall returns name, count of lexems after first word and function of call that
+	// starts with given word from modules or function resolvers. Function is nil if it is
+	// not resolved.
+	resolveCall := func(first string) (string, int, *Function, error) {
+		if len(l.funcResolvers) == 0 && len(l.modules) == 0 {
+			return "", 0, nil, nil
+		}
+		name, n, isCall := callName(first)
+		if !isCall {
+			return "", 0, nil, nil
+		}
+		if fn, isModule, err := l.moduleFunction(name); isModule {
+			return name, n, fn, err
+		}
+		fn, ok := resolved[name]
+		if !ok {
+			var err error
+			if fn, err = l.resolveFunction(ctx, name); err != nil {
+				return "", 0, nil, err
+			}
+			resolved[name] = fn
+		}
+		return name, n, fn, nil
+	}
 	go func() {
 		defer close(out)
 		for {
-			select {
-			case <-ctx.Done():
+			lexem, ok := next()
+			if !ok {
 				return
-			case lexem, ok := <-lexems:
-				if !ok {
+			}
+			start, end = lexem.Start, lexem.End
+			switch {
+			case lexem.Type == lp:
+				emit(Token{
+					typ: lp,
+				})
+			case lexem.Type == rp:
+				emit(Token{
+					typ: rp,
+				})
+			case lexem.Type == lb && prefixPos:
+				// Array literal.
+				emit(Token{
+					typ: lb,
+				})
+			case lexem.Type == lb:
+				// Index of preceding operand.
+				emit(Token{
+					typ: index,
+				})
+			case lexem.Type == rb:
+				emit(Token{
+					typ: rb,
+				})
+			case lexem.Type == lc:
+				emit(Token{
+					typ: lc,
+				})
+			case lexem.Type == rc:
+				emit(Token{
+					typ: rc,
+				})
+			case lexem.Type == sep:
+				emit(Token{
+					typ: sep,
+				})
+			case lexem.Type == cond:
+				emit(Token{
+					typ: cond,
+				})
+			case lexem.Type == colon:
+				emit(Token{
+					typ: colon,
+				})
+			case lexem.Type == number && strings.Contains(lexem.Value, "."):
+				fvalue, err := strconv.ParseFloat(lexem.Value, 64)
+				if err != nil {
+					emit(Token{
+						typ: tokError,
+						err: fmt.Errorf("%w: invalid number %s", ErrSyntax, lexem.Value),
+					})
 					return
 				}
-				switch {
-				case lexem.Type == lp:
-					out <- Token{
-						typ: lp,
-					}
-				case lexem.Type == rp:
-					out <- Token{
-						typ: rp,
-					}
-				case lexem.Type == sep:
-					out <- Token{
-						typ: sep,
-					}
-				case lexem.Type == number:
-					ivalue, _ := strconv.Atoi(lexem.Value)
-					out <- Token{
-						typ:    number,
-						ivalue: ivalue,
-					}
-				case lexem.Type == str:
-					out <- Token{
-						typ:   str,
-						value: lexem.Value,
-					}
-				case lexem.Type == op:
-					o, isOp := l.operators[lexem.Value]
+				emit(Token{
+					typ:    float,
+					fvalue: fvalue,
+				})
+			case lexem.Type == number:
+				ivalue, err := strconv.ParseInt(lexem.Value, 10, 64)
+				if err != nil {
+					emit(Token{
+						typ: tokError,
+						err: fmt.Errorf("%w: invalid number %s", ErrSyntax, lexem.Value),
+					})
+					return
+				}
+				emit(Token{
+					typ:    number,
+					ivalue: ivalue,
+				})
+			case lexem.Type == boolean:
+				emit(Token{
+					typ:    boolean,
+					bvalue: lexem.Value == "true",
+				})
+			case lexem.Type == str:
+				emit(Token{
+					typ:   str,
+					value: lexem.Value,
+				})
+			case lexem.Type == op && lexem.Value == "." && !prefixPos && isMethodCall():
+				// Method call `obj.name(args)`.
+				name, _ := next()
+				start, end = name.Start, name.End
+				emit(Token{
+					typ:   method,
+					value: name.Value,
+				})
+			case lexem.Type == op:
+				for _, name := range l.splitOps(lexem.Value) {
+					end = start + len(name)
+					tkn, isOp := l.operatorToken(name, prefixPos)
 					if !isOp {
-						out <- Token{
-							typ:   tokError,
-							value: fmt.Sprintf("unknown operator: %s", lexem.Value),
-						}
+						emit(Token{
+							typ: tokError,
+							err: fmt.Errorf("%w: %s", ErrUnknownOperator, name),
+						})
 						return
 					}
-					out <- Token{
-						typ:       op,
-						value:     lexem.Value,
-						priority:  o.priority,
-						leftAssoc: o.leftAssoc,
-					}
-				case lexem.Type == word:
-					o, isOp := l.operators[lexem.Value]
-					_, isFunc := l.functions[lexem.Value]
-					switch {
-					case isOp:
-						out <- Token{
-							typ:       op,
-							value:     lexem.Value,
-							priority:  o.priority,
-							leftAssoc: o.leftAssoc,
-						}
-					case isFunc:
-						out <- Token{
-							typ:   funct,
-							value: lexem.Value,
-						}
-					default:
-						out <- Token{
-							typ:   word,
-							value: lexem.Value,
-						}
-					}
-				case lexem.Type == tokError:
-					out <- Token{
-						typ:   tokError,
+					emit(tkn)
+					start = end
+				}
+			case lexem.Type == word:
+				tkn, isOp := l.operatorToken(lexem.Value, prefixPos)
+				fn, isFunc := l.functions[lexem.Value]
+				var (
+					name string
+					n    int
+					rfn  *Function
+					err  error
+				)
+				_, isVariable := l.variables[strings.ToLower(lexem.Value)]
+				if !isOp && !isFunc && !afterDot && !isVariable {
+					// Call on variable is method call.
+					name, n, rfn, err = resolveCall(lexem.Value)
+				}
+				switch {
+				case isOp:
+					emit(tkn)
+				case isFunc:
+					emit(Token{
+						typ:   funct,
 						value: lexem.Value,
+						call:  &callInfo{minArgs: fn.minArgs, maxArgs: fn.maxArgs},
+					})
+				case err != nil:
+					emit(Token{
+						typ: tokError,
+						err: err,
+					})
+					return
+				case rfn != nil:
+					// Skip rest of dotted name.
+					for i := 0; i < n; i++ {
+						lx, _ := next()
+						end = lx.End
 					}
+					emit(Token{
+						typ:   funct,
+						value: name,
+						call:  &callInfo{minArgs: rfn.minArgs, maxArgs: rfn.maxArgs, fn: rfn},
+					})
+				case l.strict && !l.isKnownIdentifier(lexem.Value, afterDot || isMapKey()):
+					emit(Token{
+						typ: tokError,
+						err: fmt.Errorf("%w: %s", ErrUnknownIdentifier, lexem.Value),
+					})
 					return
+				default:
+					emit(Token{
+						typ:    word,
+						value:  lexem.Value,
+						member: afterDot,
+					})
 				}
+			case lexem.Type == tokError:
+				emit(Token{
+					typ: tokError,
+					err: fmt.Errorf("%w: unexpected symbol %s", ErrSyntax, lexem.Value),
+				})
+				return
 			}
 		}
 	}()
 	return out
 }
+
+// operatorToken returns token of operator with given name. Operators at prefix position
+// are looked up at prefix operators.
+func (l *Lexpr) operatorToken(name string, prefixPos bool) (Token, bool) {
+	if prefixPos {
+		o, isOp := l.prefixOperators[name]
+		return Token{
+			typ:      prefix,
+			value:    name,
+			priority: o.priority,
+		}, isOp
+	}
+	o, isOp := l.operators[name]
+	return Token{
+		typ:       op,
+		value:     name,
+		priority:  o.priority,
+		leftAssoc: o.leftAssoc,
+		jump:      o.jump,
+	}, isOp
+}
+
+// moduleFunction returns function of module by dotted name, like `str.upper`. Returns
+// false if name is not call of registered module.
+func (l *Lexpr) moduleFunction(name string) (*Function, bool, error) {
+	parts := strings.Split(name, ".")
+	if len(parts) != 2 {
+		return nil, false, nil
+	}
+	moduleName := strings.ToLower(parts[0])
+	module, ok := l.modules[moduleName]
+	if !ok {
+		return nil, false, nil
+	}
+	if l.imports != nil && !l.imports[moduleName] {
+		return nil, true, fmt.Errorf("%w: module %s is not imported", ErrUnknownIdentifier, parts[0])
+	}
+	fn, ok := module[strings.ToLower(parts[1])]
+	if !ok {
+		return nil, true, fmt.Errorf("%w: function %s", ErrUnknownIdentifier, name)
+	}
+	return &fn, true, nil
+}
+
+// resolveFunction returns function from function resolvers, nil if function is unknown.
+func (l *Lexpr) resolveFunction(ctx context.Context, name string) (*Function, error) {
+	for _, r := range l.funcResolvers {
+		fn, ok, err := r.ResolveFunction(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &fn, nil
+		}
+	}
+	return nil, nil
+}
+
+// isKnownIdentifier returns true if name is Lexpr variable, allowed key on the right
+// of member access operator or at map literal, or name resolved by StrictResolver.
+func (l *Lexpr) isKnownIdentifier(name string, isKey bool) bool {
+	if _, ok := l.variables[strings.ToLower(name)]; ok {
+		return true
+	}
+	if isKey {
+		return l.allowedKeys[strings.ToLower(name)]
+	}
+	for _, r := range l.resolvers {
+		if sr, ok := r.(StrictResolver); ok && sr.Resolves(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOps splits sequence of operator chars to known operators, longest first.
+// For example `*-` splits to `*` and `-`. Unknown rest of sequence returned as is.
+func (l *Lexpr) splitOps(s string) []string {
+	ops := []string{}
+	for s != "" {
+		i := len(s)
+		for ; i > 0; i-- {
+			_, isOp := l.operators[s[:i]]
+			_, isPrefix := l.prefixOperators[s[:i]]
+			if isOp || isPrefix {
+				break
+			}
+		}
+		if i == 0 {
+			return append(ops, s)
+		}
+		ops = append(ops, s[:i])
+		s = s[i:]
+	}
+	return ops
+}
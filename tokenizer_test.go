@@ -71,6 +71,7 @@ func TestLexpr_tokenize(t *testing.T) {
 				{
 					typ:   funct,
 					value: "min",
+					call:  &callInfo{minArgs: 2, maxArgs: 2},
 				},
 				{
 					typ: lp,
@@ -99,6 +100,7 @@ func TestLexpr_tokenize(t *testing.T) {
 				{
 					typ:   funct,
 					value: "max",
+					call:  &callInfo{minArgs: 2, maxArgs: 2},
 				},
 				{
 					typ: lp,
@@ -130,12 +132,76 @@ func TestLexpr_tokenize(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "prefix operators",
+			args: args{
+				lexems: []lexem{
+					{
+						Type:  op,
+						Value: "-",
+						Start: 0,
+						End:   1,
+					}, {
+						Type:  number,
+						Value: "2",
+						Start: 1,
+						End:   2,
+					}, {
+						Type:  op,
+						Value: "*-",
+						Start: 2,
+						End:   4,
+					}, {
+						Type:  word,
+						Value: "x",
+						Start: 4,
+						End:   5,
+					},
+				},
+			},
+			want: []Token{
+				{
+					typ:      prefix,
+					value:    "-",
+					priority: 125,
+					start:    0,
+					end:      1,
+				},
+				{
+					typ:    number,
+					ivalue: 2,
+					start:  1,
+					end:    2,
+				},
+				{
+					typ:      op,
+					value:    "*",
+					priority: 120,
+					start:    2,
+					end:      3,
+				},
+				{
+					typ:      prefix,
+					value:    "-",
+					priority: 125,
+					start:    3,
+					end:      4,
+				},
+				{
+					typ:   word,
+					value: "x",
+					start: 4,
+					end:   5,
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			l := &Lexpr{
-				operators: Operators,
-				functions: Functions,
+				operators:       Operators,
+				prefixOperators: PrefixOperators,
+				functions:       Functions,
 			}
 			lexemsCh := make(chan lexem)
 			go func() {